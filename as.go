@@ -0,0 +1,48 @@
+package errsel
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// As returns a selector that will match the first error in an error's
+// context chain that is assignable to T, or that implements an
+// As(interface{}) bool method which reports true for a *T, following the
+// semantics of the standard library's errors.As.
+//
+// Any provided traverse options will scope to causes.
+func As[T any](opts ...TraverseOption) Selector {
+	t := reflect.TypeOf((*T)(nil)).Elem()
+	return describe(fmt.Sprintf("as(%v)", t), Causes(func(err error) bool {
+		_, ok := as[T](err)
+		return ok
+	}, opts...))
+}
+
+// AsValue traverses an error's context chain and extracts the first error
+// that As[T] would match.
+//
+//    if perr, ok := AsValue[*os.PathError](err); ok {
+//        // handle perr
+//    }
+func AsValue[T any](err error, opts ...TraverseOption) (T, bool) {
+	var v T
+	ok := Causes(func(err error) bool {
+		var ok bool
+		v, ok = as[T](err)
+		return ok
+	}, opts...).In(err)
+	return v, ok
+}
+
+func as[T any](err error) (T, bool) {
+	if v, ok := err.(T); ok {
+		return v, true
+	}
+
+	var v T
+	if x, ok := err.(interface{ As(interface{}) bool }); ok && x.As(&v) {
+		return v, true
+	}
+	return v, false
+}
@@ -0,0 +1,46 @@
+package errsel
+
+import (
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+type asErr struct{}
+
+func (asErr) Error() string { return "as" }
+
+func (asErr) As(target interface{}) bool {
+	if p, ok := target.(*tempErr); ok {
+		*p = tempErr{code: 42}
+		return true
+	}
+	return false
+}
+
+func TestAs(t *testing.T) {
+	perr := &os.PathError{Op: "open", Path: "/nope", Err: os.ErrNotExist}
+	err := Named("fs").Wrap(fmt.Errorf("load: %w", perr), "config")
+
+	ok, er := As[*os.PathError]().Traverse(err)
+	assert.True(t, ok)
+	assert.Equal(t, perr, er)
+	assert.False(t, As[*os.LinkError]().In(err))
+
+	v, ok := AsValue[*os.PathError](err)
+	assert.True(t, ok)
+	assert.Equal(t, "/nope", v.Path)
+
+	_, ok = AsValue[*os.PathError](err, Depth(2))
+	assert.False(t, ok)
+
+	te, ok := AsValue[tempErr](errors.Wrap(asErr{}, "wrapped"))
+	assert.True(t, ok)
+	assert.Equal(t, 42, te.code)
+
+	_, ok = AsValue[tempErr](nil)
+	assert.False(t, ok)
+}
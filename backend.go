@@ -0,0 +1,240 @@
+package errsel
+
+import (
+	stderrors "errors"
+	"fmt"
+	"sync/atomic"
+
+	"github.com/pkg/errors"
+)
+
+// Backend constructs and wraps the errors that lifters operate on.
+//
+// Lifters use the package-wide backend set by SetBackend unless bound to
+// a specific one with LifterFunc.Using.
+type Backend interface {
+	New(msg string) error
+	Errorf(format string, args ...interface{}) error
+
+	WithStack(err error) error
+	WithMessage(err error, msg string) error
+
+	Wrap(err error, msg string) error
+	Wrapf(err error, format string, args ...interface{}) error
+
+	// Unwrap returns the error that err wraps, or nil if there is none.
+	Unwrap(err error) error
+}
+
+var (
+	// PkgErrors is a backend built on github.com/pkg/errors. It attaches
+	// stack traces to new and wrapped errors. This is the default.
+	PkgErrors Backend = pkgBackend{}
+
+	// Stdlib is a backend built on the standard library. Wrapped errors
+	// are compatible with errors.Unwrap, and no stack traces are attached.
+	Stdlib Backend = stdBackend{}
+
+	// Compat is a backend that attaches stack traces like PkgErrors, but
+	// whose wrapped errors are also compatible with errors.Unwrap. Its
+	// Errorf supports the %w verb.
+	Compat Backend = compatBackend{}
+)
+
+type backendBox struct{ Backend }
+
+var defaultBackend atomic.Value
+
+func init() {
+	defaultBackend.Store(backendBox{PkgErrors})
+}
+
+// SetBackend sets the package-wide backend used by lifters that are not
+// bound to a specific one. It is safe for concurrent use, but should
+// typically be called once during program initialization.
+func SetBackend(b Backend) {
+	defaultBackend.Store(backendBox{b})
+}
+
+// CurrentBackend returns the package-wide backend.
+func CurrentBackend() Backend {
+	return defaultBackend.Load().(backendBox).Backend
+}
+
+type pkgBackend struct{}
+
+func (pkgBackend) New(msg string) error {
+	return errors.New(msg)
+}
+
+func (pkgBackend) Errorf(format string, args ...interface{}) error {
+	return errors.Errorf(format, args...)
+}
+
+func (pkgBackend) WithStack(err error) error {
+	return errors.WithStack(err)
+}
+
+func (pkgBackend) WithMessage(err error, msg string) error {
+	return errors.WithMessage(err, msg)
+}
+
+func (pkgBackend) Wrap(err error, msg string) error {
+	return errors.Wrap(err, msg)
+}
+
+func (pkgBackend) Wrapf(err error, format string, args ...interface{}) error {
+	return errors.Wrapf(err, format, args...)
+}
+
+func (pkgBackend) Unwrap(err error) error {
+	if c, ok := err.(causer); ok {
+		return c.Cause()
+	}
+	return nil
+}
+
+type stdBackend struct{}
+
+func (stdBackend) New(msg string) error {
+	return stderrors.New(msg)
+}
+
+func (stdBackend) Errorf(format string, args ...interface{}) error {
+	return fmt.Errorf(format, args...)
+}
+
+func (stdBackend) WithStack(err error) error {
+	return err
+}
+
+func (stdBackend) WithMessage(err error, msg string) error {
+	if err == nil {
+		return nil
+	}
+	return fmt.Errorf("%s: %w", msg, err)
+}
+
+func (b stdBackend) Wrap(err error, msg string) error {
+	return b.WithMessage(err, msg)
+}
+
+func (b stdBackend) Wrapf(err error, format string, args ...interface{}) error {
+	return b.WithMessage(err, fmt.Sprintf(format, args...))
+}
+
+func (stdBackend) Unwrap(err error) error {
+	return stderrors.Unwrap(err)
+}
+
+type compatBackend struct{}
+
+func (compatBackend) New(msg string) error {
+	return errors.New(msg)
+}
+
+func (b compatBackend) Errorf(format string, args ...interface{}) error {
+	return b.WithStack(fmt.Errorf(format, args...))
+}
+
+func (compatBackend) WithStack(err error) error {
+	if err == nil {
+		return nil
+	}
+	return stackErr{errors.WithStack(err)}
+}
+
+func (compatBackend) WithMessage(err error, msg string) error {
+	return stdBackend{}.WithMessage(err, msg)
+}
+
+func (b compatBackend) Wrap(err error, msg string) error {
+	return b.WithStack(b.WithMessage(err, msg))
+}
+
+func (b compatBackend) Wrapf(err error, format string, args ...interface{}) error {
+	return b.WithStack(b.WithMessage(err, fmt.Sprintf(format, args...)))
+}
+
+func (compatBackend) Unwrap(err error) error {
+	return stderrors.Unwrap(err)
+}
+
+// WithStackOnce is like l.WithStack, but only attaches a stack trace if
+// there isn't one anywhere in err's context chain already.
+//
+//    return errsel.WithStackOnce(conflict, err)
+func WithStackOnce(l Lifter, err error) error {
+	if hasStack(err) {
+		return l.Lift(err)
+	}
+	return l.WithStack(err)
+}
+
+// StackOnce returns a backend that constructs and wraps errors with b, but
+// only attaches stack traces to errors that don't already have one anywhere
+// in their context chain. Otherwise, binding several lifters that each wrap
+// an error would attach redundant stack traces.
+//
+//    errsel.SetBackend(errsel.StackOnce(errsel.PkgErrors))
+func StackOnce(b Backend) Backend {
+	return stackOnceBackend{b}
+}
+
+type stackOnceBackend struct {
+	Backend
+}
+
+func (b stackOnceBackend) WithStack(err error) error {
+	if hasStack(err) {
+		return err
+	}
+	return b.Backend.WithStack(err)
+}
+
+func (b stackOnceBackend) Wrap(err error, msg string) error {
+	if hasStack(err) {
+		return b.WithMessage(err, msg)
+	}
+	return b.Backend.Wrap(err, msg)
+}
+
+func (b stackOnceBackend) Wrapf(err error, format string, args ...interface{}) error {
+	if hasStack(err) {
+		return b.WithMessage(err, fmt.Sprintf(format, args...))
+	}
+	return b.Backend.Wrapf(err, format, args...)
+}
+
+var stacked = Causes(func(err error) bool {
+	_, ok := err.(stackTracer)
+	return ok
+})
+
+// hasStack reports whether any error in err's context chain has a stack
+// trace.
+func hasStack(err error) bool {
+	return err != nil && stacked.In(err)
+}
+
+// stackErr adapts a stack carrying error from github.com/pkg/errors to the
+// standard library's Unwrap.
+type stackErr struct {
+	error
+}
+
+func (e stackErr) Cause() error {
+	return e.error.(causer).Cause()
+}
+
+func (e stackErr) Unwrap() error {
+	return e.Cause()
+}
+
+func (e stackErr) StackTrace() errors.StackTrace {
+	return e.error.(stackTracer).StackTrace()
+}
+
+func (e stackErr) Format(s fmt.State, verb rune) {
+	e.error.(fmt.Formatter).Format(s, verb)
+}
@@ -0,0 +1,138 @@
+package errsel
+
+import (
+	stderrors "errors"
+	"fmt"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBackend(t *testing.T) {
+	cls := Named("io")
+	root := stderrors.New("root")
+
+	std := LifterFunc(cls.Lift).Using(Stdlib)
+	err := std.Wrap(root, "read")
+	assert.Equal(t, "io{ read: root }", err.Error())
+	assert.True(t, cls.In(err))
+	assert.True(t, stderrors.Is(err, root))
+
+	_, stacked := cls.Wrap(root, "read").(*classErr).err.(fmt.Formatter)
+	assert.True(t, stacked)
+
+	SetBackend(Stdlib)
+	defer SetBackend(PkgErrors)
+
+	err = cls.Wrapf(root, "read %d", 1)
+	assert.Equal(t, "io{ read 1: root }", err.Error())
+	assert.Equal(t, root, Stdlib.Unwrap(err.(*classErr).err))
+}
+
+func TestCompatBackend(t *testing.T) {
+	cls := Named("io")
+	root := stderrors.New("root")
+	lft := LifterFunc(cls.Lift).Using(Compat)
+
+	for _, err := range []error{
+		lft.Wrap(root, "read"),
+		lft.Wrapf(root, "read %d", 1),
+		lft.Errorf("read %d: %w", 1, root),
+		lft.WithStack(root),
+	} {
+		assert.True(t, stderrors.Is(err, root), err.Error())
+		assert.True(t, Error(root).In(err), err.Error())
+		assert.Contains(t, fmt.Sprintf("%+v", err.(*classErr).err), "backend_test.go")
+	}
+
+	assert.Equal(t, "io{ read 1: root }", lft.Errorf("read %d: %w", 1, root).Error())
+}
+
+func TestStackOnce(t *testing.T) {
+	stacks := func(err error) (n int) {
+		for e := range Chain(err) {
+			if _, ok := e.(stackTracer); ok {
+				n++
+			}
+		}
+		return n
+	}
+
+	a, b := Named("a"), Named("b")
+	root := stderrors.New("root")
+
+	assert.Equal(t, 2, stacks(a.WithStack(b.WithStack(root))))
+	assert.Equal(t, 1, stacks(WithStackOnce(a, WithStackOnce(b, root))))
+	assert.Equal(t, 1, stacks(WithStackOnce(a, b.New("new"))))
+
+	once := LifterFunc(a.Lift).Using(StackOnce(PkgErrors))
+	err := once.Wrap(once.Wrapf(b.Wrap(root, "b"), "%s", "once"), "twice")
+	assert.Equal(t, 1, stacks(err))
+	assert.Equal(t, "a{ twice: a{ once: b{ b: root } } }", err.Error())
+	assert.Equal(t, 1, stacks(once.WithStack(once.WithStack(root))))
+}
+
+func TestLazyStacks(t *testing.T) {
+	cls := Named("io")
+	root := stderrors.New("root")
+	lft := LifterFunc(cls.Lift).Using(LazyStacks)
+
+	for _, err := range []error{
+		lft.New("new"),
+		lft.Wrap(root, "read"),
+		lft.Wrapf(root, "read %d", 1),
+		lft.Errorf("read %d: %w", 1, root),
+		lft.WithStack(root),
+	} {
+		inner := err.(*classErr).err
+		assert.Implements(t, (*stackTracer)(nil), inner)
+		assert.Contains(t, fmt.Sprintf("%+v", inner), "backend_test.go")
+		assert.NotContains(t, fmt.Sprintf("%v", inner), "backend_test.go")
+		assert.Equal(t, inner.Error(), fmt.Sprintf("%s", inner))
+	}
+
+	err := lft.Wrap(root, "read")
+	assert.Equal(t, "io{ read: root }", err.Error())
+	assert.True(t, stderrors.Is(err, root))
+	assert.Equal(t, "new", errors.Cause(lft.New("new")).Error())
+
+	// as with PkgErrors, stacks begin at the lifter
+	st := err.(*classErr).err.(stackTracer).StackTrace()
+	assert.Equal(t, "backendLifter.Wrap", fmt.Sprintf("%n", st[0]))
+	assert.Equal(t, "TestLazyStacks", fmt.Sprintf("%n", st[1]))
+}
+
+func TestUseBackend(t *testing.T) {
+	root := stderrors.New("root")
+	lazy := Named("lazy", UseBackend(LazyStacks))
+	none := lazy.Child("none", UseBackend(Stdlib))
+	msg := lazy.Child("msg", DefaultMessage("failed"))
+
+	_, ok := lazy.Wrap(root, "x").(*classErr).err.(*lazyWrap)
+	assert.True(t, ok)
+	assert.False(t, hasStack(none.Wrap(root, "x")))
+	assert.False(t, hasStack(none.New("x")))
+
+	err := msg.Lift(root)
+	assert.Equal(t, "msg{ failed: root }", err.Error())
+	assert.False(t, hasStack(err))
+	assert.True(t, hasStack(msg.New("x")))
+	assert.True(t, lazy.In(err))
+}
+
+func BenchmarkNewPkgErrors(b *testing.B) {
+	cls := Named("hot")
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		_ = cls.New("x")
+	}
+}
+
+func BenchmarkNewLazyStacks(b *testing.B) {
+	cls := Named("hot", UseBackend(LazyStacks))
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		_ = cls.New("x")
+	}
+}
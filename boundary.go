@@ -0,0 +1,59 @@
+package errsel
+
+// Boundary translates errors crossing a package or service boundary. Errors
+// of mapped internal classes are lifted into their external counterparts,
+// while every internal class is hidden beneath a shadowing class.
+//
+//    var api = errsel.NewBoundary("api").
+//        Map(storage.NotFound, NotFound).
+//        Map(storage.Conflict, Conflict)
+//
+//    func (s *Service) Get(id string) (*Item, error) {
+//        item, err := s.db.Get(id)
+//        return item, api.Lift(err)
+//    }
+//
+// A Boundary is itself a class, which matches every error that has crossed
+// it. Mappings should be added during initialization, before the boundary
+// is used.
+type Boundary struct {
+	Class
+	shadow Class
+	rules  []boundaryRule
+}
+
+type boundaryRule struct {
+	internal Selector
+	external Lifter
+}
+
+// NewBoundary returns a boundary without any mappings, which hides internal
+// classes beneath a named, shadowing class. Any provided options configure
+// the shadowing class, such as MintBypass.
+func NewBoundary(name string, opts ...ClassOption) *Boundary {
+	b := &Boundary{shadow: NamedShadow(name, opts...)}
+	b.Class = ToClass(LifterFunc(b.lift), b.shadow)
+	return b
+}
+
+// Map maps errors matching internal to the external class. Mappings are
+// tried in the order they were added, and only the first match applies. It
+// returns b, so that calls can be chained.
+func (b *Boundary) Map(internal Selector, external Lifter) *Boundary {
+	b.rules = append(b.rules, boundaryRule{internal, external})
+	return b
+}
+
+func (b *Boundary) Query(err error) (error, bool) {
+	return queryOf(b, err)
+}
+
+func (b *Boundary) lift(err error) error {
+	shadowed := b.shadow.Lift(err)
+	for _, r := range b.rules {
+		if r.internal.In(err) {
+			return r.external.Lift(shadowed)
+		}
+	}
+	return shadowed
+}
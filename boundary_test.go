@@ -0,0 +1,31 @@
+package errsel
+
+import (
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBoundary(t *testing.T) {
+	missing, conflict, down := Named("storage.missing"), Named("storage.conflict"), Named("storage.down")
+	notFound, exists := Named("not_found"), Named("exists")
+
+	api := NewBoundary("api").
+		Map(missing, notFound).
+		Map(conflict, exists)
+
+	err := api.Lift(errors.Wrap(missing.New("no rows"), "get"))
+	assert.Equal(t, "not_found{ api#{ get: storage.missing{ no rows } } }", err.Error())
+	assert.True(t, notFound.In(err))
+	assert.True(t, api.In(err))
+	assert.False(t, missing.In(err))
+
+	err = api.Wrap(down.New("dial"), "get")
+	assert.True(t, api.In(err))
+	assert.False(t, down.In(err))
+	assert.False(t, Or(notFound, exists).In(err))
+
+	assert.True(t, exists.In(api.Lift(conflict.New("stale"))))
+	assert.Nil(t, api.Lift(nil))
+}
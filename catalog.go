@@ -0,0 +1,114 @@
+package errsel
+
+import (
+	"context"
+	"strings"
+)
+
+// Catalog resolves classes into localized message templates, using the same
+// {name} placeholder syntax as Template.
+type Catalog interface {
+	// Lookup returns the template for errors of cls in locale, if the
+	// catalog has one.
+	Lookup(locale string, cls Class) (string, bool)
+}
+
+// MapCatalog is a catalog of templates keyed by locale, then by class name.
+// Lookups fall back from regional locales such as "pt-BR" to their base
+// language, and from classes to their parents.
+//
+//    var catalog = errsel.MapCatalog{
+//        "en": {"quota": "quota exceeded for {user}"},
+//        "de": {"quota": "Kontingent für {user} überschritten"},
+//    }
+type MapCatalog map[string]map[string]string
+
+func (m MapCatalog) Lookup(locale string, cls Class) (string, bool) {
+	for {
+		if msgs, ok := m[locale]; ok {
+			for c := cls; c != nil; c = ParentOf(c) {
+				if name, ok := nameOf(c); ok {
+					if tmpl, ok := msgs[name]; ok {
+						return tmpl, true
+					}
+				}
+			}
+		}
+
+		i := strings.LastIndexAny(locale, "-_")
+		if i < 0 {
+			return "", false
+		}
+		locale = locale[:i]
+	}
+}
+
+func nameOf(cls Class) (string, bool) {
+	if c, ok := cls.(*errClass); ok && c.cls != nil && c.cls.named {
+		return c.cls.name, true
+	}
+	return "", false
+}
+
+type localeKey struct{}
+
+// WithLocale returns a copy of ctx carrying locale, for use with Localize.
+func WithLocale(ctx context.Context, locale string) context.Context {
+	return context.WithValue(ctx, localeKey{}, locale)
+}
+
+// LocaleFrom returns the locale carried by ctx, if any.
+func LocaleFrom(ctx context.Context) (string, bool) {
+	locale, ok := ctx.Value(localeKey{}).(string)
+	return locale, ok
+}
+
+// Localize renders err into a message in the locale carried by ctx, using
+// the template cat has for the outermost class in err's context chain that
+// it knows about, respecting class shadowing. Templates are rendered with
+// the args of errors constructed with NewT, falling back to any fields
+// attached to the chain.
+//
+//    ctx = errsel.WithLocale(ctx, "de")
+//    if msg, ok := errsel.Localize(ctx, catalog, err); ok {
+//        // show msg to the user
+//    }
+//
+// If ctx carries no locale, or cat has no template for any class in the
+// chain, Localize returns false.
+//
+// Any provided traverse options will scope to classes.
+func Localize(ctx context.Context, cat Catalog, err error, opts ...TraverseOption) (string, bool) {
+	locale, ok := LocaleFrom(ctx)
+	if !ok {
+		return "", false
+	}
+
+	var msg string
+	ok = Classes(func(e error) bool {
+		ce := e.(*classErr)
+
+		var tmpl string
+		for cls := range ce.classes {
+			if tmpl, ok = cat.Lookup(locale, cls.self); ok {
+				break
+			}
+		}
+		if !ok {
+			return false
+		}
+
+		args := FieldsOf(err)
+		if t, ok := ce.err.(*templateErr); ok {
+			if args == nil {
+				args = make(Fields, len(t.args))
+			}
+			for k, v := range t.args {
+				args[k] = v
+			}
+		}
+		msg = render(tmpl, args)
+		return true
+	}, opts...).In(err)
+	return msg, ok
+}
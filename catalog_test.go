@@ -0,0 +1,39 @@
+package errsel
+
+import (
+	"context"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLocalize(t *testing.T) {
+	quota := Named("quota", Template("quota exceeded for {user}"))
+	storage := quota.Child("storage")
+
+	catalog := MapCatalog{
+		"en": {"quota": "quota exceeded for {user}"},
+		"de": {"quota": "Kontingent für {user} überschritten", "storage": "Speicher voll ({region})"},
+	}
+
+	err := errors.Wrap(quota.NewT(Fields{"user": "alice"}), "upload")
+	ctx := WithLocale(context.Background(), "de-AT")
+
+	msg, ok := Localize(ctx, catalog, err)
+	assert.True(t, ok)
+	assert.Equal(t, "Kontingent für alice überschritten", msg)
+
+	msg, _ = Localize(ctx, catalog, storage.WithFields(storage.NewT(nil), Fields{"region": "eu"}))
+	assert.Equal(t, "Speicher voll (eu)", msg)
+
+	msg, _ = Localize(WithLocale(context.Background(), "en"), catalog, storage.NewT(Fields{"user": "bob"}))
+	assert.Equal(t, "quota exceeded for bob", msg)
+
+	_, ok = Localize(WithLocale(context.Background(), "fr"), catalog, err)
+	assert.False(t, ok)
+	_, ok = Localize(context.Background(), catalog, err)
+	assert.False(t, ok)
+	_, ok = Localize(ctx, catalog, NamedShadow("api").Lift(err))
+	assert.False(t, ok)
+}
@@ -1,8 +1,6 @@
 package errsel
 
-import (
-	"github.com/pkg/errors"
-)
+import "reflect"
 
 // Class is an interface for things that are both lifters and selectors.
 //
@@ -13,6 +11,46 @@ import (
 type Class interface {
 	Lifter
 	Selector
+
+	// Sentinel returns an error that can be used as the target of the
+	// standard library's errors.Is to test for the class, without
+	// importing errsel selectors.
+	//
+	//    if errors.Is(err, databaseClass.Sentinel()) {
+	//        // handle the error
+	//    }
+	//
+	// The standard library does not know about shadowing, so errors.Is
+	// will see classes hidden beneath a shadowing class.
+	Sentinel() error
+
+	// Child returns a named class that descends from this one. When used
+	// as a selector, a class will match errors lifted into any of its
+	// descendants, while each descendant remains independently selectable.
+	//
+	//    var database = Named("database")
+	//    var conflict = database.Child("conflict")
+	//
+	//    database.In(conflict.New("oh no")) // true
+	Child(name string, opts ...ClassOption) Class
+
+	// Err returns a new error lifted into the class, using the class's
+	// default message. Classes without a default message use their name.
+	//
+	//    var conflict = Named("conflict", DefaultMessage("resource version conflict"))
+	//
+	//    conflict.Err() // conflict{ resource version conflict }
+	Err() error
+
+	// NewT returns a new error lifted into the class, with a message
+	// rendered from the class's template and args. The args are kept
+	// alongside the error, and can be retrieved with ArgsOf.
+	//
+	//    var quota = Named("quota", Template("quota exceeded for {user}: {limit}"))
+	//
+	//    quota.NewT(Fields{"user": "alice", "limit": 10})
+	//    // quota{ quota exceeded for alice: 10 }
+	NewT(args Fields) error
 }
 
 var _ Class = new(errClass)
@@ -20,25 +58,271 @@ var _ Class = new(errClass)
 type errClass struct {
 	Lifter
 	Selector
+	sentinel *sentinel
+	cls      *class
+	fused    []*class // the classes bound into this one, if fused
 }
 
 func ToClass(lft Lifter, sel Selector) Class {
 	return &errClass{
 		Lifter:   lft,
 		Selector: sel,
+		sentinel: &sentinel{sel},
+	}
+}
+
+func (c *errClass) Sentinel() error {
+	return c.sentinel
+}
+
+func (c *errClass) Child(name string, opts ...ClassOption) Class {
+	if c.cls != nil {
+		return c.cls.child(name, opts)
+	}
+	// classes derived from arbitrary lifters and selectors can't recognize
+	// descendants, so lift into both instead
+	return Bind(c, Named(name, opts...))
+}
+
+func (c *errClass) Err() error {
+	if c.cls != nil {
+		return c.New(c.cls.message())
+	}
+	return c.New(Describe(c.Selector))
+}
+
+func (c *errClass) NewT(args Fields) error {
+	if c.cls != nil {
+		return c.cls.lift(newTemplateErr(c.cls.template(), args))
 	}
+	return c.Lift(newTemplateErr(Describe(c.Selector), args))
+}
+
+// ParentOf returns the class that cls descends from, or nil if it has no
+// parent.
+func ParentOf(cls Class) Class {
+	if c, ok := cls.(*errClass); ok && c.cls != nil && c.cls.parent != nil {
+		return c.cls.parent.self
+	}
+	return nil
+}
+
+func (c *errClass) MatchBool(err error) bool {
+	if c.cls != nil {
+		return c.cls.match(err)
+	}
+	return matchBool(c.Selector, err)
+}
+
+func (c *errClass) In(err error) bool {
+	return c.MatchBool(err)
+}
+
+func (c *errClass) Query(err error) (error, bool) {
+	return queryOf(c, err)
+}
+
+// String describes the class by its selector.
+func (c *errClass) String() string {
+	return Describe(c.Selector)
+}
+
+// sentinel stands in for a class as the target of errors.Is.
+type sentinel struct {
+	sel Selector
+}
+
+func (s *sentinel) Error() string {
+	return "errsel: class sentinel"
 }
 
 func FromClass(cls Class) (Lifter, Selector) {
 	return LifterFunc(cls.Lift), SelectorFunc(cls.Traverse)
 }
 
+// Seal returns a tamper-proof handle to cls, suitable for exporting as a
+// package level variable. Classes are configured upon construction, and
+// their configuration can't be changed afterwards; but some classes, such as
+// a *TypedClass or *Boundary, expose fields or methods that can. The sealed
+// handle hides those, exposing only the Class interface.
+//
+//    var conflict = errsel.NamedClassOf[string]("conflict")
+//
+//    var Conflict = errsel.Seal(conflict)
+//
+// Classes built with Named, Anonymous and friends are already sealed, and
+// are returned as is.
+func Seal(cls Class) Class {
+	switch c := cls.(type) {
+	case *errClass, sealed:
+		return c
+	}
+	return sealed{cls}
+}
+
+// sealed hides everything but the Class methods of a class.
+type sealed struct {
+	Class
+}
+
+func (s sealed) Query(err error) (error, bool) {
+	return queryOf(s, err)
+}
+
+// MaskLifter returns a lifter that masks the lifter half of the provided
+// class. It is the complement of Mask; this can be useful if you want to
+// export a class for the creation of new error instances, while disallowing
+// its use as a selector.
+//
+//    // producers can create storage errors, but never branch on them
+//    var Storage = errsel.MaskLifter(storage)
+func MaskLifter(cls Lifter) Lifter {
+	return lifterMask{cls}
+}
+
+// lifterMask hides everything but the Lifter methods of a class.
+type lifterMask struct {
+	Lifter
+}
+
+// Bind returns a class that lifts errors into both f and g, and selects
+// errors that were lifted into both.
+//
+// If f and g were constructed by this package, their traversals are fused
+// into a single pass over the context chain, rather than one for every
+// bound class. Errors are then also lifted into every bound class with a
+// single annotation, rather than one nested within another, unless any of
+// them shadow or alter the errors they lift. Messages are rendered as though
+// the annotations were nested:
+//
+//    Bind(api, storage).New("down").Error() // api{ storage{ down } }
 func Bind(f, g Class) Class {
-	// TODO: this should be optimized...
-	//       we should be able to fuse their traversal functions
-	//       into a single pass over the context chain, instead
-	//       of traversing for every bound class
-	return ToClass(f.Bind(g), And(f, g))
+	fs, gs := constituents(f), constituents(g)
+	if fs == nil || gs == nil || len(fs)+len(gs) > 64 {
+		return ToClass(f.Bind(g), And(f, g))
+	}
+
+	cs := append(append(make([]*class, 0, len(fs)+len(gs)), fs...), gs...)
+
+	lft := f.Bind(g)
+	if bindable(cs) {
+		lft = LifterFunc(func(err error) error {
+			return liftBound(cs, err)
+		})
+		if b := cs[0].backendOf(); b != nil {
+			lft = lft.(LifterFunc).Using(b)
+		}
+	}
+
+	c := ToClass(lft, compose("and", fuse(cs), f, g)).(*errClass)
+	c.fused = cs
+	return c
+}
+
+// bindable reports whether errors can be lifted into every one of cs with
+// a single annotation, rather than one per class. Classes that alter the
+// errors they lift, or that shadow, need an annotation of their own, as do
+// classes that construct errors with different backends.
+func bindable(cs []*class) bool {
+	for _, cls := range cs {
+		if !sameBackend(cls.backendOf(), cs[0].backendOf()) {
+			return false
+		}
+		if cls.shadow || cls.when != nil || cls.soft || cls.pool || cls.dep != nil {
+			return false
+		}
+		if _, ok := cls.defaultMessage(); ok {
+			return false
+		}
+		for p := cls; p != nil; p = p.parent {
+			if p.secret || p.scrub != nil || p.icept != nil {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// sameBackend reports whether a and b are the same backend. Backends that
+// can't be compared are never the same.
+func sameBackend(a, b Backend) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	t := reflect.TypeOf(a)
+	return t == reflect.TypeOf(b) && t.Comparable() && a == b
+}
+
+// liftBound lifts err into every one of cs with a single annotation, as
+// though it had been lifted into each of them from the last to the first.
+func liftBound(cs []*class, err error) error {
+	c := &classErr{cls: cs[0], also: cs[1:], err: err}
+	for _, cls := range cs {
+		countLift(cls)
+		if c.inst == nil {
+			c.inst = cls.instance()
+		}
+	}
+	return c
+}
+
+// constituents returns the classes that cls is composed of, or nil if it
+// can't be fused.
+func constituents(cls Class) []*class {
+	c, ok := cls.(*errClass)
+	switch {
+	case !ok:
+		return nil
+	case c.cls != nil:
+		return []*class{c.cls}
+	default:
+		return c.fused
+	}
+}
+
+// fuse returns a selector that will match if every one of cs annotates an
+// error's context chain, in a single traversal. It respects class shadowing,
+// as Classes does.
+func fuse(cs []*class) Selector {
+	all := ^uint64(0) >> (64 - len(cs))
+	cfg := applyTraverseOpts()
+
+	return SelectorFunc(func(err error) (bool, error) {
+		var seen uint64
+		if c, ok := err.(*classErr); ok && !tracing() {
+			for _, x := range c.index().errs {
+				for i, cls := range cs {
+					if seen&(1<<uint(i)) == 0 && cls.in(x) {
+						seen |= 1 << uint(i)
+					}
+				}
+				if seen == all {
+					countMatches(cs)
+					return true, err
+				}
+			}
+			return false, nil
+		}
+
+		cfg.walk(err, func(f frame) (bool, bool) {
+			c, ok := f.err.(*classErr)
+			if !ok {
+				return false, true
+			}
+			for i, cls := range cs {
+				if seen&(1<<uint(i)) == 0 && cls.in(c) {
+					seen |= 1 << uint(i)
+				}
+			}
+			return seen == all, !c.shadow
+		})
+
+		if seen == all {
+			countMatches(cs)
+			return true, err
+		}
+		return false, nil
+	})
 }
 
 func Binds(f Class, gs ...Class) Class {
@@ -87,6 +371,21 @@ type Lifter interface {
 
 	Wrap(err error, msg string) error
 	Wrapf(err error, format string, args ...interface{}) error
+
+	// WithValue lifts err, and attaches val to the result under key, which
+	// can be retrieved with ValueOf. Values are attached outside of the
+	// lift, so that they remain visible above shadowing classes.
+	WithValue(err error, key, val interface{}) error
+
+	// WithFields lifts err, and attaches structured fields to the result,
+	// which can be retrieved with FieldsOf. Like values, fields are attached
+	// outside of the lift.
+	WithFields(err error, fields Fields) error
+
+	// WrapPublic wraps err with an internal message like Wrap, and attaches
+	// a public message that is safe to show to clients, which can be
+	// retrieved with PublicMessageOf.
+	WrapPublic(err error, msg, public string) error
 }
 
 // LifterFunc lifts an error to another scope.
@@ -112,25 +411,106 @@ func (f LifterFunc) Bind(lft Lifter) Lifter {
 }
 
 func (f LifterFunc) New(msg string) error {
-	return f(errors.New(msg))
+	return f(CurrentBackend().New(msg))
 }
 
 func (f LifterFunc) Errorf(format string, args ...interface{}) error {
-	return f(errors.Errorf(format, args...))
+	return f(CurrentBackend().Errorf(format, args...))
 }
 
 func (f LifterFunc) WithStack(err error) error {
-	return f(errors.WithStack(err))
+	return f(CurrentBackend().WithStack(err))
 }
 
 func (f LifterFunc) WithMessage(err error, msg string) error {
-	return f(errors.WithMessage(err, msg))
+	return f(CurrentBackend().WithMessage(err, msg))
 }
 
 func (f LifterFunc) Wrap(err error, msg string) error {
-	return f(errors.Wrap(err, msg))
+	return f(CurrentBackend().Wrap(err, msg))
 }
 
 func (f LifterFunc) Wrapf(err error, format string, args ...interface{}) error {
-	return f(errors.Wrapf(err, format, args...))
+	return f(CurrentBackend().Wrapf(err, format, args...))
+}
+
+func (f LifterFunc) WithValue(err error, key, val interface{}) error {
+	return withValue(f.Lift(err), key, val)
+}
+
+func (f LifterFunc) WithFields(err error, fields Fields) error {
+	return withFields(f.Lift(err), fields)
+}
+
+func (f LifterFunc) WrapPublic(err error, msg, public string) error {
+	if err == nil {
+		return nil
+	}
+	return withPublic(f.Wrap(err, msg), public)
+}
+
+// Scrub returns a lifter that sanitizes the messages of errors with the
+// provided scrubbers before lifting them with f.
+func (f LifterFunc) Scrub(scrubbers ...Scrubber) LifterFunc {
+	return func(err error) error {
+		return f(scrub(err, scrubbers))
+	}
+}
+
+// Using returns a lifter that lifts errors with f, but constructs and wraps
+// them with the provided backend instead of the package-wide one.
+func (f LifterFunc) Using(b Backend) Lifter {
+	return backendLifter{f: f, b: b}
+}
+
+type backendLifter struct {
+	f LifterFunc
+	b Backend
+}
+
+func (l backendLifter) Lift(err error) error {
+	return l.f.Lift(err)
+}
+
+func (l backendLifter) Bind(lft Lifter) Lifter {
+	return l.f.Bind(lft).(LifterFunc).Using(l.b)
+}
+
+func (l backendLifter) New(msg string) error {
+	return l.f(l.b.New(msg))
+}
+
+func (l backendLifter) Errorf(format string, args ...interface{}) error {
+	return l.f(l.b.Errorf(format, args...))
+}
+
+func (l backendLifter) WithStack(err error) error {
+	return l.f(l.b.WithStack(err))
+}
+
+func (l backendLifter) WithMessage(err error, msg string) error {
+	return l.f(l.b.WithMessage(err, msg))
+}
+
+func (l backendLifter) Wrap(err error, msg string) error {
+	return l.f(l.b.Wrap(err, msg))
+}
+
+func (l backendLifter) Wrapf(err error, format string, args ...interface{}) error {
+	return l.f(l.b.Wrapf(err, format, args...))
+}
+
+func (l backendLifter) WithValue(err error, key, val interface{}) error {
+	return withValue(l.f.Lift(err), key, val)
+}
+
+func (l backendLifter) WithFields(err error, fields Fields) error {
+	return withFields(l.f.Lift(err), fields)
+}
+
+func (l backendLifter) WrapPublic(err error, msg, public string) error {
+	if err == nil {
+		return nil
+	}
+	return withPublic(l.Wrap(err, msg), public)
 }
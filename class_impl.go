@@ -1,9 +1,42 @@
 package errsel
 
+import (
+	"sync"
+	"sync/atomic"
+)
+
 type class struct {
-	named  bool
-	name   string
-	shadow bool
+	named   bool
+	name    string
+	shadow  bool
+	parent  *class
+	meta    map[string]interface{}
+	code    *int
+	tags    []string
+	alias   []string
+	id      string
+	msg     string
+	tmpl    string
+	secret  bool
+	scrub   []Scrubber
+	inst    bool
+	when    func(error) bool
+	soft    bool
+	dep     *deprecation
+	icept   []Interceptor
+	pool    bool
+	backend Backend
+	self    Class
+	stats   atomic.Pointer[classStats] // set once its registry collects stats
+
+	// interned symbols of name, alias and id; see intern
+	nameSym  uint32
+	aliasSym []uint32
+	idSym    uint32
+
+	// loose classes aren't interned, and are compared by string instead;
+	// see Registry.resolve
+	loose bool
 }
 
 // Anonymous returns an anonymous class.
@@ -12,19 +45,19 @@ type class struct {
 //
 // Due to its dependence on an address comparison, it should probably
 // not cross package boundaries.
-func Anonymous() Class {
-	return (&class{}).toClass()
+func Anonymous(opts ...ClassOption) Class {
+	return (&class{}).apply(opts)
 }
 
 // Named returns a named class.
 //
 // When used as a selector, it will match against any other named
 // class with exactly the same name.
-func Named(name string) Class {
+func Named(name string, opts ...ClassOption) Class {
 	return (&class{
 		named: true,
 		name:  name,
-	}).toClass()
+	}).apply(opts)
 }
 
 // AnonymousShadow returns an anonymous, shadowing class. Wrapping
@@ -33,10 +66,10 @@ func Named(name string) Class {
 // segment internal and external errors.
 //
 // When used as a selector, it will match only against itself.
-func AnonymousShadow() Class {
+func AnonymousShadow(opts ...ClassOption) Class {
 	return (&class{
 		shadow: true,
-	}).toClass()
+	}).apply(opts)
 }
 
 // NamedShadow returns a named, shadowing class. Wrapping an error
@@ -46,26 +79,227 @@ func AnonymousShadow() Class {
 //
 // When used as a selector, it will match against any other named
 // class with exactly the same name.
-func NamedShadow(name string) Class {
+func NamedShadow(name string, opts ...ClassOption) Class {
 	return (&class{
 		named:  true,
 		name:   name,
 		shadow: true,
-	}).toClass()
+	}).apply(opts)
+}
+
+// SoftShadow makes a class hide the decorations of deeper classes in its
+// error messages, without hiding the classes themselves from traversal. This
+// keeps messages clean for users, while selection continues to work.
+//
+//    var api = Named("api", SoftShadow())
+//
+//    api.Lift(database.New("down")).Error() // api{ down }
+func SoftShadow() ClassOption {
+	return ClassOption(func(e *class) {
+		e.soft = true
+	})
+}
+
+// ShadowIf makes a class shadow conditionally. Lifting an error into it
+// will hide deeper class definitions only if pred holds for the error being
+// lifted. This can be used to hide internal classes only for errors that
+// aren't already safe to export.
+//
+//    var internal = Named("internal", ShadowIf(func(err error) bool {
+//        return !exported.In(err)
+//    }))
+//
+// The predicate is evaluated once, when an error is lifted.
+func ShadowIf(pred func(error) bool) ClassOption {
+	return ClassOption(func(e *class) {
+		e.when = pred
+	})
+}
+
+func (e *class) apply(opts []ClassOption) Class {
+	for _, f := range opts {
+		f(e)
+	}
+
+	if e.loose {
+		return e.toClass()
+	}
+	if e.named {
+		e.nameSym = intern(e.name)
+		for _, a := range e.alias {
+			e.aliasSym = append(e.aliasSym, intern(a))
+		}
+	}
+	if e.id != "" {
+		e.idSym = intern(e.id)
+	}
+	return e.toClass()
+}
+
+var (
+	symbolsMu sync.Mutex
+	symbols   = make(map[string]uint32)
+)
+
+// intern returns a process-wide symbol for s, so that classes can be
+// compared by integer rather than by string. Symbols are never zero.
+func intern(s string) uint32 {
+	symbolsMu.Lock()
+	defer symbolsMu.Unlock()
+
+	sym, ok := symbols[s]
+	if !ok {
+		sym = uint32(len(symbols) + 1)
+		symbols[s] = sym
+	}
+	return sym
+}
+
+// lookup returns the symbol of s, or zero if it hasn't been interned.
+func lookup(s string) uint32 {
+	symbolsMu.Lock()
+	defer symbolsMu.Unlock()
+	return symbols[s]
+}
+
+// symbols returns the symbols of e's name, aliases and identity, looking
+// them up rather than interning them if e is loose. Symbols that were never
+// interned are zero, as no interned class could have them.
+func (e *class) symbols() (name uint32, alias []uint32, id uint32) {
+	if !e.loose {
+		return e.nameSym, e.aliasSym, e.idSym
+	}
+	if e.named {
+		name = lookup(e.name)
+		for _, a := range e.alias {
+			if sym := lookup(a); sym != 0 {
+				alias = append(alias, sym)
+			}
+		}
+	}
+	if e.id != "" {
+		id = lookup(e.id)
+	}
+	return name, alias, id
 }
 
 func (e *class) toClass() Class {
-	return ToClass(LifterFunc(e.lift), Classes(e.in))
+	b := e.backendOf()
+
+	var lft Lifter = LifterFunc(e.lift)
+	if b != nil {
+		lft = backendLifter{f: e.lift, b: b}
+	}
+	if msg, ok := e.defaultMessage(); ok {
+		lft = msgLifter{Lifter: lft, msg: msg, b: b}
+	}
+
+	c := ToClass(lft, describe(e.String(), SelectorFunc(e.traverse))).(*errClass)
+	c.cls = e
+	e.self = c
+	return e.self
+}
+
+// child returns a named class that descends from e.
+func (e *class) child(name string, opts []ClassOption) Class {
+	return (&class{
+		named:  true,
+		name:   name,
+		parent: e,
+	}).apply(opts)
+}
+
+// String describes the class as it is rendered in error messages.
+func (e *class) String() string {
+	name := "anonymous"
+	if e.named {
+		name = e.name
+	}
+	if e.shadow {
+		name += "#"
+	}
+	return name
 }
 
 func (e *class) in(err error) bool {
-	if c, ok := err.(*classErr); ok {
-		if c.cls == e {
+	c, ok := err.(*classErr)
+	if !ok {
+		return false
+	}
+	for cls := c.cls; cls != nil; cls = cls.parent {
+		if e.is(cls) {
+			return true
+		}
+	}
+	for _, b := range c.also {
+		for cls := b; cls != nil; cls = cls.parent {
+			if e.is(cls) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// is reports whether cls is the same class as e.
+func (e *class) is(cls *class) bool {
+	if e.same(cls) {
+		return true
+	}
+	if e.dep != nil && e.dep.replacement != nil && e.dep.replacement.same(cls) {
+		return true
+	}
+	return cls.dep != nil && cls.dep.replacement != nil && cls.dep.replacement.same(e)
+}
+
+// same reports whether cls is the same class as e, disregarding deprecation.
+func (e *class) same(cls *class) bool {
+	if cls == e {
+		return true
+	}
+	if cls.loose || e.loose {
+		return e.sameLoose(cls)
+	}
+	if cls.idSym != 0 && e.idSym != 0 {
+		return cls.idSym == e.idSym
+	}
+	if cls.nameSym == 0 || e.nameSym == 0 {
+		return false
+	}
+	if cls.nameSym == e.nameSym {
+		return true
+	}
+
+	for _, a := range e.aliasSym {
+		if a == cls.nameSym {
+			return true
+		}
+	}
+	for _, a := range cls.aliasSym {
+		if a == e.nameSym {
 			return true
 		}
+		for _, b := range e.aliasSym {
+			if a == b {
+				return true
+			}
+		}
+	}
+	return false
+}
 
-		if c.cls.named && e.named {
-			if c.cls.name == e.name {
+// sameLoose is same for classes that aren't both interned, comparing their
+// identities, names and aliases as strings.
+func (e *class) sameLoose(cls *class) bool {
+	if cls.id != "" && e.id != "" {
+		return cls.id == e.id
+	}
+	if !cls.named || !e.named {
+		return false
+	}
+	for _, a := range append([]string{e.name}, e.alias...) {
+		for _, b := range append([]string{cls.name}, cls.alias...) {
+			if a == b {
 				return true
 			}
 		}
@@ -74,30 +308,143 @@ func (e *class) in(err error) bool {
 }
 
 func (e *class) lift(err error) error {
+	return e.liftVal(err, nil)
+}
+
+// liftVal lifts err into e, carrying val as its payload.
+func (e *class) liftVal(err error, val interface{}) error {
+	if e.dep != nil {
+		e.dep.warn(e)
+	}
+	countLift(e)
+	err = e.intercepted(err)
+	if e.pool {
+		c := classErrPool.Get().(*classErr)
+		c.cls, c.err, c.val = e, e.scrubbed(err), val
+		c.shadow, c.inst = e.shadows(err), e.instance()
+		return c
+	}
 	return &classErr{
-		cls: e,
-		err: err,
+		cls:    e,
+		err:    e.scrubbed(err),
+		val:    val,
+		shadow: e.shadows(err),
+		inst:   e.instance(),
 	}
 }
 
+// shadows reports whether lifting err into e will hide any deeper class
+// definitions.
+func (e *class) shadows(err error) bool {
+	return e.shadow || e.when != nil && e.when(err)
+}
+
 type classErr struct {
-	cls *class
-	err error
+	cls    *class
+	also   []*class // further classes of a bound class, outer to inner
+	err    error
+	val    interface{}
+	shadow bool
+	inst   *Instance
+	idx    atomic.Pointer[classIndex]
+}
+
+// classes yields the classes c was lifted into, from the outermost inward.
+// Errors lifted into a bound class carry more than one.
+func (c *classErr) classes(yield func(*class) bool) {
+	if !yield(c.cls) {
+		return
+	}
+	for _, cls := range c.also {
+		if !yield(cls) {
+			return
+		}
+	}
 }
 
 func (c *classErr) Error() string {
+	if c.cls.soft && !c.cls.sensitive() {
+		return c.decorate(undecorated(c.err))
+	}
+	return c.decorate(c.message())
+}
+
+// message returns the message of the error this class wraps.
+func (c *classErr) message() string {
+	if c.cls.sensitive() {
+		return Redacted
+	}
+	return c.err.Error()
+}
+
+var decorations atomic.Bool
+
+func init() {
+	decorations.Store(true)
+}
+
+// SetDecorations sets whether class decorations such as name{ ... } are
+// rendered in error messages. Decorations are useful during development,
+// but can leak an internal taxonomy of errors in production responses.
+// They are enabled by default.
+//
+//    errsel.SetDecorations(os.Getenv("ENV") != "production")
+//
+// Selection is unaffected. It is safe for concurrent use, but should
+// typically be called once during program initialization.
+func SetDecorations(on bool) {
+	decorations.Store(on)
+}
+
+// decorate renders msg as the message of the error this class wraps.
+func (c *classErr) decorate(msg string) string {
+	if !decorations.Load() {
+		return msg
+	}
+
+	for i := len(c.also) - 1; i >= 0; i-- {
+		if c.also[i].named {
+			msg = c.also[i].name + "{ " + msg + " }"
+		}
+	}
+
 	var shad string
-	if c.cls.shadow {
+	if c.shadow {
 		shad = "#"
 	}
 
 	if c.cls.named {
-		return c.cls.name + shad + "{ " + c.err.Error() + " }"
+		return c.cls.name + shad + "{ " + msg + " }"
 	}
 
-	return c.err.Error()
+	return msg
 }
 
 func (c *classErr) Cause() error {
 	return c.err
 }
+
+// Unwrap allows classed errors to participate in chain inspection by
+// the standard library's errors.Is and errors.As.
+func (c *classErr) Unwrap() error {
+	return c.err
+}
+
+// Is reports whether target is the sentinel of a class that matches this
+// error, for use with the standard library's errors.Is.
+func (c *classErr) Is(target error) bool {
+	if s, ok := target.(*sentinel); ok {
+		return s.sel.In(c)
+	}
+	return false
+}
+
+// As sets target to the class this error was lifted into, if target is a
+// *Class, for use with the standard library's errors.As.
+func (c *classErr) As(target interface{}) bool {
+	if p, ok := target.(*Class); ok {
+		*p = c.cls.self
+		return true
+	}
+	return false
+}
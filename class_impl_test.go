@@ -0,0 +1,220 @@
+package errsel
+
+import (
+	stderrors "errors"
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClassErrUnwrap(t *testing.T) {
+	cls := Named("fs")
+	err := cls.Lift(&os.PathError{Op: "open", Path: "/nope", Err: os.ErrNotExist})
+
+	assert.True(t, stderrors.Is(err, os.ErrNotExist))
+
+	var perr *os.PathError
+	assert.True(t, stderrors.As(err, &perr))
+	assert.Equal(t, "/nope", perr.Path)
+
+	assert.Equal(t, stderrors.Unwrap(err), err.(*classErr).Cause())
+}
+
+func TestClassErrIsAs(t *testing.T) {
+	database, conflict, other := Named("database"), NamedShadow("conflict"), Named("other")
+	err := fmt.Errorf("query: %w", conflict.Lift(database.New("btree")))
+
+	assert.True(t, stderrors.Is(err, database.Sentinel()))
+	assert.True(t, stderrors.Is(err, conflict.Sentinel()))
+	assert.True(t, stderrors.Is(err, Named("database").Sentinel()))
+
+	// bound sentinels match like their selectors, which respect shadowing
+	assert.Equal(t, Bind(conflict, database).In(err), stderrors.Is(err, Bind(conflict, database).Sentinel()))
+	assert.False(t, stderrors.Is(err, other.Sentinel()))
+	assert.False(t, stderrors.Is(err, Bind(conflict, other).Sentinel()))
+
+	var cls Class
+	assert.True(t, stderrors.As(err, &cls))
+	assert.Equal(t, conflict, cls)
+	assert.True(t, cls.In(err))
+}
+
+func TestChild(t *testing.T) {
+	database := Named("database")
+	conflict := database.Child("conflict")
+	deadlock := conflict.Child("deadlock")
+	missing := database.Child("missing")
+
+	err := deadlock.New("tx aborted")
+	assert.Equal(t, "deadlock{ tx aborted }", err.Error())
+
+	assert.True(t, database.In(err))
+	assert.True(t, conflict.In(err))
+	assert.True(t, deadlock.In(err))
+	assert.True(t, Named("conflict").In(err))
+	assert.False(t, missing.In(err))
+
+	assert.False(t, deadlock.In(conflict.New("stale")))
+	assert.False(t, conflict.In(database.New("down")))
+
+	assert.Equal(t, conflict, ParentOf(deadlock))
+	assert.Equal(t, database, ParentOf(conflict))
+	assert.Nil(t, ParentOf(database))
+
+	bound := Bind(Anonymous(), database)
+	child := bound.Child("child")
+	assert.True(t, bound.In(child.New("x")))
+	assert.Nil(t, ParentOf(child))
+}
+
+func TestShadowIf(t *testing.T) {
+	exported, secret := Named("exported"), Named("secret")
+	internal := Named("internal", ShadowIf(Not(exported).In))
+
+	hidden := internal.Lift(secret.New("disk full"))
+	assert.Equal(t, "internal#{ secret{ disk full } }", hidden.Error())
+	assert.True(t, internal.In(hidden))
+	assert.False(t, secret.In(hidden))
+
+	visible := internal.Lift(exported.New("bad input"))
+	assert.Equal(t, "internal{ exported{ bad input } }", visible.Error())
+	assert.True(t, exported.In(visible))
+}
+
+func TestMaskLifter(t *testing.T) {
+	conflict := Named("conflict", DefaultMessage("resource version conflict"))
+	lft := MaskLifter(conflict)
+
+	_, ok := lft.(Selector)
+	assert.False(t, ok)
+
+	assert.True(t, conflict.In(lft.New("stale")))
+	assert.Equal(t, conflict.New("stale").Error(), lft.New("stale").Error())
+	assert.Equal(t, "conflict{ resource version conflict: stale }", lft.Lift(stderrors.New("stale")).Error())
+}
+
+func TestSoftShadow(t *testing.T) {
+	api, database, secret := Named("api", SoftShadow()), Named("database"), Named("secret", Sensitive())
+
+	err := api.Lift(fmt.Errorf("get: %w", database.Lift(secret.New("password"))))
+	assert.Equal(t, "api{ get: [REDACTED] }", err.Error())
+	assert.True(t, database.In(err))
+	assert.True(t, secret.In(err))
+
+	err = Named("outer").Lift(api.Lift(database.Lift(AnonymousShadow().New("down"))))
+	assert.Equal(t, "outer{ api{ down } }", err.Error())
+
+	// only decorations are stripped, not text that looks like them
+	err = api.Lift(errors.Wrap(database.New("x"), "database{ x }"))
+	assert.Equal(t, "api{ database{ x }: x }", err.Error())
+
+	// sensitivity takes precedence over soft shadowing
+	err = Named("x", Sensitive(), SoftShadow()).New("password")
+	assert.Equal(t, "x{ [REDACTED] }", err.Error())
+	assert.Equal(t, "x{ password }", Unredacted(err))
+}
+
+func TestSetDecorations(t *testing.T) {
+	database, shadow := Named("database"), NamedShadow("api")
+	lift := func() error {
+		return shadow.Lift(errors.Wrap(database.New("down"), "get"))
+	}
+
+	SetDecorations(false)
+	defer SetDecorations(true)
+
+	assert.Equal(t, "get: down", lift().Error())
+	assert.True(t, Classes(database.In, IgnoreShadow()).In(lift()))
+	assert.Equal(t, "[REDACTED]", Named("secret", Sensitive()).New("down").Error())
+
+	SetDecorations(true)
+	assert.Equal(t, "api#{ get: database{ down } }", lift().Error())
+}
+
+func TestBindFused(t *testing.T) {
+	a, b, c, shadow := Named("a"), Named("b"), Anonymous(), NamedShadow("shadow")
+	abc := Binds(a, b, c)
+	assert.Len(t, abc.(*errClass).fused, 3)
+
+	err := abc.New("x")
+	assert.True(t, abc.In(err))
+	assert.True(t, Bind(c, a).In(err))
+	assert.False(t, abc.In(a.Lift(b.New("x"))))
+	assert.True(t, abc.In(c.Lift(fmt.Errorf("wrap: %w", a.Lift(b.New("x"))))))
+	assert.False(t, abc.In(c.Lift(shadow.Lift(a.Lift(b.New("x"))))))
+	outer := Bind(abc, shadow)
+	assert.True(t, outer.In(outer.New("x")))
+	assert.False(t, outer.In(shadow.Lift(abc.New("x"))))
+
+	ok, er := abc.Traverse(err)
+	assert.True(t, ok)
+	assert.Equal(t, err, er)
+	assert.Equal(t, "and(and(a, b), anonymous)", Describe(abc))
+
+	// classes from elsewhere fall back to And
+	mixed := Bind(a, ToClass(LifterFunc(b.Lift), b))
+	assert.Nil(t, mixed.(*errClass).fused)
+	assert.True(t, mixed.In(mixed.New("x")))
+}
+
+func TestInternedNames(t *testing.T) {
+	a, b, c := Named("conflict"), Named("conflict"), Named("other", Alias("conflict"))
+	ca, cb, cc := a.(*errClass).cls, b.(*errClass).cls, c.(*errClass).cls
+	assert.NotZero(t, ca.nameSym)
+	assert.Equal(t, ca.nameSym, cb.nameSym)
+	assert.NotEqual(t, ca.nameSym, cc.nameSym)
+	assert.Equal(t, []uint32{ca.nameSym}, cc.aliasSym)
+	assert.Zero(t, Anonymous().(*errClass).cls.nameSym)
+
+	assert.True(t, a.In(b.New("x")))
+	assert.True(t, c.In(a.New("x")))
+	assert.False(t, Named("unrelated").In(a.New("x")))
+}
+
+func TestBindSingleAnnotation(t *testing.T) {
+	a := Named("a", Code(400), Tags("t"))
+	b := Named("b.x", Code(500))
+	c := Anonymous()
+	abc := Binds(a, b, c)
+
+	err := abc.New("down")
+	ce := err.(*classErr)
+	assert.Equal(t, "down", errors.Cause(ce.err).Error())
+	assert.Equal(t, "a{ b.x{ down } }", err.Error())
+
+	for _, sel := range []Selector{a, b, c, abc, Bind(c, a), Prefix("b"), Tagged("t"),
+		NamedGlob("b.*"), MustQuery("/class[name='b.x']"), AnyOf(Named("zzz"), b)} {
+		assert.True(t, sel.In(err), Describe(sel))
+	}
+	assert.Equal(t, []Class{a, b, c}, ClassesOf(err))
+	code, _ := CodeOf(err)
+	assert.Equal(t, 500, code)
+
+	var bound []Class
+	Walk(err, func(e error, info FrameInfo) bool {
+		if info.Class != nil {
+			bound = append([]Class{info.Class}, info.Bound...)
+		}
+		return true
+	})
+	assert.Equal(t, []Class{a, b, c}, bound)
+	assert.Equal(t, []string{"b.x", "anonymous"}, Snapshot(err).Frames[0].Bound)
+	assert.True(t, Grep("a{ b.x{ down").In(err))
+
+	// classes that shadow or alter errors keep annotations of their own
+	nested := Bind(a, NamedShadow("s")).New("down")
+	assert.Nil(t, nested.(*classErr).also)
+	assert.Equal(t, "a{ s#{ down } }", nested.Error())
+	assert.Nil(t, Bind(a, Named("m", DefaultMessage("m"))).Lift(stderrors.New("x")).(*classErr).also)
+
+	// as do classes that construct errors with another backend
+	stackless := Named("stackless", UseBackend(Stdlib))
+	mixed := Bind(a, stackless).New("down")
+	assert.Nil(t, mixed.(*classErr).also)
+	assert.Equal(t, "a{ stackless{ down } }", mixed.Error())
+	assert.Nil(t, StackOf(Bind(stackless, Named("other", UseBackend(Stdlib))).New("down")))
+	assert.NotNil(t, Bind(stackless, Named("other", UseBackend(Stdlib))).New("down").(*classErr).also)
+}
@@ -0,0 +1,360 @@
+package errsel
+
+import (
+	"crypto/rand"
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// ClassOption configures a class upon construction.
+type ClassOption func(*class)
+
+// Meta attaches static metadata to a class under key. It can be retrieved
+// from errors lifted into the class with MetaOf.
+//
+//    var conflict = Named("conflict", Meta("retryable", true), Meta("http", 409))
+func Meta(key string, val interface{}) ClassOption {
+	return ClassOption(func(e *class) {
+		if e.meta == nil {
+			e.meta = make(map[string]interface{})
+		}
+		e.meta[key] = val
+	})
+}
+
+// MetaOf returns the metadata stored under key by the outermost class in
+// err's context chain that has it, respecting class shadowing. Classes
+// inherit the metadata of their parents.
+//
+// Any provided traverse options will scope to classes.
+func MetaOf(err error, key string, opts ...TraverseOption) (interface{}, bool) {
+	var (
+		val   interface{}
+		found bool
+	)
+	Classes(func(e error) bool {
+		for cls := range e.(*classErr).classes {
+			if val, found = cls.lookup(key); found {
+				break
+			}
+		}
+		return found
+	}, opts...).In(err)
+	return val, found
+}
+
+// Code associates a numeric code with a class, such as a wire or status
+// code. It can be retrieved from errors lifted into the class with CodeOf.
+//
+//    var conflict = Named("conflict", Code(1409))
+func Code(code int) ClassOption {
+	return ClassOption(func(e *class) {
+		e.code = &code
+	})
+}
+
+// CodeOf returns the code of the innermost class in err's context chain
+// that has one, respecting class shadowing. Classes inherit the code of
+// their parents.
+//
+// Any provided traverse options will scope to classes.
+func CodeOf(err error, opts ...TraverseOption) (int, bool) {
+	var code *int
+	Classes(func(e error) bool {
+		if c := e.(*classErr).code(); c != nil {
+			code = c
+		}
+		return false
+	}, opts...).In(err)
+
+	if code == nil {
+		return 0, false
+	}
+	return *code, true
+}
+
+// code returns the code of the innermost class c was lifted into that has
+// one, or nil if none do.
+func (c *classErr) code() *int {
+	var code *int
+	for b := range c.classes {
+		for cls := b; cls != nil; cls = cls.parent {
+			if cls.code != nil {
+				code = cls.code
+				break
+			}
+		}
+	}
+	return code
+}
+
+// Alias declares alternative names for a named class, to support renaming
+// classes without breaking selectors elsewhere. Selecting on the class
+// will match errors lifted under any of its aliases, and selecting on an
+// alias will match errors lifted into the class.
+//
+//    var notFound = Named("not_found", Alias("missing"))
+//
+//    Named("missing").In(notFound.New("no rows")) // true
+func Alias(names ...string) ClassOption {
+	return ClassOption(func(e *class) {
+		e.alias = append(e.alias, names...)
+	})
+}
+
+// Tags attaches free-form tags to a class, for cross-cutting categories of
+// errors that don't fit a hierarchy. Errors can be selected by tag with
+// Tagged.
+//
+//    var timeout = Named("timeout", Tags("transient", "client"))
+func Tags(tags ...string) ClassOption {
+	return ClassOption(func(e *class) {
+		e.tags = append(e.tags, tags...)
+	})
+}
+
+// Tagged returns a selector that will match if any class in an error's
+// context chain carries the provided tag. Classes inherit the tags of their
+// parents.
+//
+// Any provided traverse options will scope to classes.
+func Tagged(tag string, opts ...TraverseOption) Selector {
+	return describe("tagged("+strconv.Quote(tag)+")", Classes(func(err error) bool {
+		for cls := range err.(*classErr).classes {
+			if cls.tagged(tag) {
+				return true
+			}
+		}
+		return false
+	}, opts...))
+}
+
+// Prefix returns a selector that will match if any named class in an
+// error's context chain is within the provided dot separated namespace.
+// For example, Prefix("storage.sql") matches classes named "storage.sql" and
+// "storage.sql.conflict", but not "storage.sqlite". Classes are also within
+// the namespaces of their parents.
+//
+// Any provided traverse options will scope to classes.
+func Prefix(prefix string, opts ...TraverseOption) Selector {
+	return describe("prefix("+strconv.Quote(prefix)+")", Classes(func(err error) bool {
+		for b := range err.(*classErr).classes {
+			for cls := b; cls != nil; cls = cls.parent {
+				if cls.named && inNamespace(cls.name, prefix) {
+					return true
+				}
+			}
+		}
+		return false
+	}, opts...))
+}
+
+// NamedGlob returns a selector that will match if any named class in an
+// error's context chain has a name matching the provided glob pattern.
+// Patterns are matched segment by segment, where segments are separated by
+// dots: "*" matches exactly one segment, "**" matches any number of
+// segments, and within a segment the syntax of path.Match applies.
+//
+//    NamedGlob("db.*.timeout") // matches db.read.timeout, db.write.timeout
+//    NamedGlob("db.**")        // matches anything within db
+//
+// NamedGlob panics if the pattern is malformed.
+//
+// Any provided traverse options will scope to classes.
+func NamedGlob(pattern string, opts ...TraverseOption) Selector {
+	segs := strings.Split(pattern, ".")
+	for _, seg := range segs {
+		if _, err := path.Match(seg, ""); err != nil {
+			panic("errsel: malformed glob pattern " + strconv.Quote(pattern))
+		}
+	}
+
+	return describe("glob("+strconv.Quote(pattern)+")", Classes(func(err error) bool {
+		for cls := range err.(*classErr).classes {
+			if cls.named && globMatch(segs, strings.Split(cls.name, ".")) {
+				return true
+			}
+		}
+		return false
+	}, opts...))
+}
+
+func globMatch(pattern, name []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			for i := 0; i <= len(name); i++ {
+				if globMatch(pattern[1:], name[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+
+		if len(name) == 0 {
+			return false
+		}
+		if ok, _ := path.Match(pattern[0], name[0]); !ok {
+			return false
+		}
+		pattern, name = pattern[1:], name[1:]
+	}
+	return len(name) == 0
+}
+
+func inNamespace(name, prefix string) bool {
+	if !strings.HasPrefix(name, prefix) {
+		return false
+	}
+	return len(name) == len(prefix) || prefix == "" || name[len(prefix)] == '.'
+}
+
+func (e *class) tagged(tag string) bool {
+	for cls := e; cls != nil; cls = cls.parent {
+		for _, t := range cls.tags {
+			if t == tag {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (e *class) lookup(key string) (interface{}, bool) {
+	for cls := e; cls != nil; cls = cls.parent {
+		if val, ok := cls.meta[key]; ok {
+			return val, true
+		}
+	}
+	return nil, false
+}
+
+// DefaultMessage sets the message of errors constructed with the class's
+// Err method. Lifting an existing error into the class will wrap it with the
+// message as well, so that wording stays consistent between call sites.
+// Classes inherit the default message of their parents.
+//
+//    var conflict = Named("conflict", DefaultMessage("resource version conflict"))
+//
+//    conflict.Lift(err) // conflict{ resource version conflict: <err> }
+//
+// Errors constructed with New, Errorf, Wrap and friends use the message
+// they are given instead.
+func DefaultMessage(msg string) ClassOption {
+	return ClassOption(func(e *class) {
+		e.msg = msg
+	})
+}
+
+func (e *class) defaultMessage() (string, bool) {
+	for cls := e; cls != nil; cls = cls.parent {
+		if cls.msg != "" {
+			return cls.msg, true
+		}
+	}
+	return "", false
+}
+
+// message returns the message of errors constructed with Err.
+func (e *class) message() string {
+	if msg, ok := e.defaultMessage(); ok {
+		return msg
+	}
+	if e.named {
+		return e.name
+	}
+	return "anonymous"
+}
+
+// UseBackend makes a class construct and wrap errors with b, rather than
+// the package-wide backend, as LifterFunc.Using does. Classes inherit this
+// from their parents. It can be used to make stack traces cheaper for
+// classes on hot paths, or to disable them entirely:
+//
+//    var timeout = Named("timeout", UseBackend(LazyStacks))
+//    var notFound = Named("not_found", UseBackend(Stdlib))
+func UseBackend(b Backend) ClassOption {
+	return ClassOption(func(e *class) {
+		e.backend = b
+	})
+}
+
+func (e *class) backendOf() Backend {
+	for cls := e; cls != nil; cls = cls.parent {
+		if cls.backend != nil {
+			return cls.backend
+		}
+	}
+	return nil
+}
+
+// msgLifter lifts errors into a class with a default message, wrapping them
+// with the message upon Lift. Errors constructed with New, Wrap and friends
+// are left to the embedded lifter, which doesn't add the message.
+type msgLifter struct {
+	Lifter
+	msg string
+	b   Backend // if nil, the package-wide backend
+}
+
+func (l msgLifter) Lift(err error) error {
+	if err == nil {
+		return nil
+	}
+	b := l.b
+	if b == nil {
+		b = CurrentBackend()
+	}
+	return l.Lifter.Lift(b.WithMessage(err, l.msg))
+}
+
+func (l msgLifter) Bind(lft Lifter) Lifter {
+	f := LifterFunc(func(err error) error {
+		return l.Lift(lft.Lift(err))
+	})
+	if l.b != nil {
+		return f.Using(l.b)
+	}
+	return f
+}
+
+func (l msgLifter) WithValue(err error, key, val interface{}) error {
+	return withValue(l.Lift(err), key, val)
+}
+
+func (l msgLifter) WithFields(err error, fields Fields) error {
+	return withFields(l.Lift(err), fields)
+}
+
+// Identity gives a class a stable identity, such as a UUID. Classes with the
+// same identity match each other as selectors, even if they are anonymous
+// or were constructed in different processes. This allows errors to be
+// serialized, rehydrated elsewhere, and still be selected.
+//
+//    var conflict = Anonymous(Identity("5b1f8f9e-8d0c-4b8e-9a57-3c2f0b1d6e42"))
+func Identity(id string) ClassOption {
+	return ClassOption(func(e *class) {
+		e.id = id
+	})
+}
+
+// NewIdentity returns a random UUID, suitable for use with Identity. It is
+// meant to be used once to generate an identity that is then hardcoded.
+func NewIdentity() string {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		panic(err)
+	}
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
+
+// IdentityOf returns the stable identity of cls, if it has one.
+func IdentityOf(cls Class) (string, bool) {
+	if c, ok := cls.(*errClass); ok && c.cls != nil && c.cls.id != "" {
+		return c.cls.id, true
+	}
+	return "", false
+}
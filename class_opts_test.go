@@ -0,0 +1,204 @@
+package errsel
+
+import (
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIdentity(t *testing.T) {
+	id := NewIdentity()
+	assert.Regexp(t, `^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`, id)
+	assert.NotEqual(t, id, NewIdentity())
+
+	// as if constructed in two different processes
+	here, there := Anonymous(Identity(id)), Anonymous(Identity(id))
+	assert.True(t, there.In(here.New("conflict")))
+	assert.True(t, Named("x", Identity(id)).In(here.New("conflict")))
+	assert.False(t, Anonymous(Identity(NewIdentity())).In(here.New("conflict")))
+	assert.False(t, Anonymous().In(here.New("conflict")))
+
+	// identities decide, whatever the names
+	named := Named("x", Identity(id))
+	assert.False(t, Named("x", Identity(NewIdentity())).In(named.New("conflict")))
+	assert.False(t, AnyOf(Named("x", Identity(NewIdentity())), Named("y")).In(named.New("conflict")))
+	assert.True(t, AnyOf(Named("x"), Named("y")).In(named.New("conflict")))
+
+	got, ok := IdentityOf(here)
+	assert.True(t, ok)
+	assert.Equal(t, id, got)
+
+	_, ok = IdentityOf(Anonymous())
+	assert.False(t, ok)
+}
+
+func TestMeta(t *testing.T) {
+	database := Named("database", Meta("retryable", false), Meta("http", 500))
+	conflict := database.Child("conflict", Meta("retryable", true))
+	shadow := AnonymousShadow(Meta("http", 400))
+
+	err := errors.Wrap(conflict.New("stale"), "update")
+
+	v, ok := MetaOf(err, "retryable")
+	assert.True(t, ok)
+	assert.Equal(t, true, v)
+
+	v, ok = MetaOf(err, "http")
+	assert.True(t, ok)
+	assert.Equal(t, 500, v)
+
+	_, ok = MetaOf(err, "missing")
+	assert.False(t, ok)
+
+	v, _ = MetaOf(shadow.Lift(err), "http")
+	assert.Equal(t, 400, v)
+	_, ok = MetaOf(shadow.Lift(err), "retryable")
+	assert.False(t, ok)
+	v, _ = MetaOf(shadow.Lift(err), "retryable", IgnoreShadow())
+	assert.Equal(t, true, v)
+}
+
+func TestCode(t *testing.T) {
+	api, storage := Named("api", Code(500)), Named("storage")
+	conflict := storage.Child("conflict", Code(1409))
+	hidden := NamedShadow("hidden", Code(400))
+
+	code, ok := CodeOf(api.Lift(errors.Wrap(conflict.New("stale"), "update")))
+	assert.True(t, ok)
+	assert.Equal(t, 1409, code)
+
+	code, ok = CodeOf(api.Lift(storage.New("down")))
+	assert.True(t, ok)
+	assert.Equal(t, 500, code)
+
+	code, _ = CodeOf(hidden.Lift(conflict.New("stale")))
+	assert.Equal(t, 400, code)
+
+	code, _ = CodeOf(conflict.Child("deadlock").New("abort"))
+	assert.Equal(t, 1409, code)
+
+	_, ok = CodeOf(storage.New("down"))
+	assert.False(t, ok)
+}
+
+func TestTagged(t *testing.T) {
+	network := Named("network", Tags("transient"))
+	timeout := network.Child("timeout", Tags("client"))
+	hidden := AnonymousShadow()
+
+	err := errors.Wrap(timeout.New("deadline"), "fetch")
+
+	assert.True(t, Tagged("transient").In(err))
+	assert.True(t, Tagged("client").In(err))
+	assert.False(t, Tagged("server").In(err))
+	assert.False(t, Tagged("client").In(network.New("reset")))
+	assert.False(t, Tagged("transient").In(hidden.Lift(err)))
+	assert.Equal(t, `tagged("client")`, Describe(Tagged("client")))
+}
+
+func TestPrefix(t *testing.T) {
+	conflict := Named("storage.sql.conflict")
+	sqlite := Named("storage.sqlite")
+	child := Named("storage.kv").Child("missing")
+
+	err := errors.Wrap(conflict.New("stale"), "update")
+
+	assert.True(t, Prefix("storage").In(err))
+	assert.True(t, Prefix("storage.sql").In(err))
+	assert.True(t, Prefix("storage.sql.conflict").In(err))
+	assert.False(t, Prefix("storage.sql.conflict.x").In(err))
+	assert.False(t, Prefix("storage.sql").In(sqlite.New("locked")))
+	assert.False(t, Prefix("stor").In(err))
+	assert.True(t, Prefix("storage.kv").In(child.New("no key")))
+	assert.False(t, Prefix("storage").In(Anonymous().New("anon")))
+}
+
+func TestNamedGlob(t *testing.T) {
+	cases := []struct {
+		pattern, name string
+		ok            bool
+	}{
+		{"db.*.timeout", "db.read.timeout", true},
+		{"db.*.timeout", "db.read.write.timeout", false},
+		{"db.*.timeout", "db.timeout", false},
+		{"db.**.timeout", "db.timeout", true},
+		{"db.**.timeout", "db.read.write.timeout", true},
+		{"db.**", "db.read", true},
+		{"db.**", "dbx.read", false},
+		{"db.r?ad.*", "db.read.x", true},
+		{"db.[rw]*", "db.write", true},
+		{"**", "anything.at.all", true},
+		{"db", "db.read", false},
+	}
+
+	for _, c := range cases {
+		t.Run(c.pattern+" "+c.name, func(t *testing.T) {
+			assert.Equal(t, c.ok, NamedGlob(c.pattern).In(Named(c.name).New("x")))
+		})
+	}
+
+	assert.False(t, NamedGlob("**").In(Anonymous().New("x")))
+	assert.Panics(t, func() { NamedGlob("db.[") })
+}
+
+func TestAlias(t *testing.T) {
+	notFound := Named("not_found", Alias("missing"))
+	missing := Named("missing")
+	absent := Named("absent", Alias("missing"))
+
+	assert.True(t, missing.In(notFound.New("no rows")))
+	assert.True(t, notFound.In(missing.New("no rows")))
+	assert.True(t, absent.In(notFound.New("no rows")))
+	assert.True(t, notFound.In(notFound.New("no rows")))
+	assert.False(t, Named("not_found").In(absent.New("no rows")))
+	assert.False(t, Named("other").In(notFound.New("no rows")))
+}
+
+func TestDefaultMessage(t *testing.T) {
+	database := Named("database", DefaultMessage("database failure"))
+	conflict := database.Child("conflict", DefaultMessage("resource version conflict"))
+	deadlock := conflict.Child("deadlock")
+
+	assert.Equal(t, "conflict{ resource version conflict }", conflict.Err().Error())
+	assert.Equal(t, "deadlock{ resource version conflict }", deadlock.Err().Error())
+	assert.Equal(t, "plain{ plain }", Named("plain").Err().Error())
+	assert.True(t, database.In(deadlock.Err()))
+
+	err := conflict.Lift(errors.New("stale"))
+	assert.Equal(t, "conflict{ resource version conflict: stale }", err.Error())
+	assert.Equal(t, "conflict{ explicit }", conflict.New("explicit").Error())
+	assert.Nil(t, conflict.Lift(nil))
+
+	err = Named("api").Bind(conflict).Lift(errors.New("stale"))
+	assert.Equal(t, "api{ conflict{ resource version conflict: stale } }", err.Error())
+
+	stdlib := Named("c", DefaultMessage("dm"), UseBackend(Stdlib))
+	assert.Equal(t, "c{ m }", stdlib.New("m").Error())
+	assert.Equal(t, "c{ dm }", stdlib.Err().Error())
+	assert.Equal(t, "c{ m: x }", stdlib.Wrap(errors.New("x"), "m").Error())
+	assert.Equal(t, "c{ dm: x }", stdlib.Lift(errors.New("x")).Error())
+	assert.Nil(t, StackOf(stdlib.New("m")))
+}
+
+func TestDeprecated(t *testing.T) {
+	notFound := Named("not_found")
+
+	var calls int
+	missing := Named("missing", Deprecated(notFound, func(old, new Class) {
+		calls++
+		assert.Equal(t, "missing", Describe(old))
+		assert.Equal(t, notFound, new)
+	}))
+
+	assert.True(t, notFound.In(missing.New("no rows")))
+	assert.True(t, missing.In(notFound.New("no rows")))
+	assert.True(t, Named("not_found").In(missing.New("no rows")))
+	assert.False(t, Named("other").In(missing.New("no rows")))
+
+	missing.Wrap(errors.New("no rows"), "get")
+	assert.Equal(t, 1, calls)
+
+	quiet := Anonymous(Deprecated(notFound, nil))
+	assert.True(t, notFound.In(quiet.New("x")))
+}
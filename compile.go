@@ -0,0 +1,196 @@
+package errsel
+
+import "reflect"
+
+// Compile returns a selector equivalent to sel, rewritten to do less work
+// per evaluation. The cost of optimization is paid once, so Compile is best
+// used when initializing package level selectors:
+//
+//    var retryable = errsel.Compile(errsel.Or(
+//        timeout, unavailable, Or(conflict, deadlock),
+//        errsel.Error(io.ErrUnexpectedEOF), errsel.Is(context.DeadlineExceeded),
+//    ))
+//
+// Compile rewrites the trees built by And and Or: nested conjunctions and
+// disjunctions are flattened, duplicate classes are dropped, classes are
+// decided together in a single traversal as AllOf and AnyOf do, and
+// selectors built by Causes (including Error, Is and Type) with the same
+// traversal options share a single traversal. Not(Not(s)) is reduced to s.
+//
+// The compiled selector matches exactly the errors that sel does, and
+// selectors with side effects, such as those built by Call, are evaluated
+// just as often. Predicates passed to Causes are assumed to be free of side
+// effects, as fused traversals may stop early. Other combinators are left
+// as they are.
+func Compile(sel Selector) Selector {
+	n, ok := sel.(*node)
+	if !ok {
+		return sel
+	}
+
+	switch n.op {
+	case "not":
+		if inner, ok := n.args[0].(*node); ok && inner.op == "not" {
+			s := Compile(inner.args[0])
+			return describe(Describe(s), Root(s.In))
+		}
+		return Not(Compile(n.args[0]))
+
+	case "and", "or":
+		return compileJunction(n.op, n.args)
+	}
+	return sel
+}
+
+// compileJunction compiles the arguments of an and or or node.
+func compileJunction(op string, args []Selector) Selector {
+	var (
+		flat    []Selector
+		flatten func([]Selector)
+	)
+	flatten = func(ss []Selector) {
+		for _, s := range ss {
+			if n, ok := s.(*node); ok && n.op == op {
+				flatten(n.args)
+				continue
+			}
+			// only whether an argument matches is significant here
+			if n, ok := s.(*node); ok && n.op == "not" {
+				if inner, ok := n.args[0].(*node); ok && inner.op == "not" {
+					flatten(inner.args)
+					continue
+				}
+			}
+
+			s = Compile(s)
+			if cs := constituents(classOf(s)); op == "and" && len(cs) > 1 {
+				for _, cls := range cs {
+					flat = append(flat, cls.self)
+				}
+				continue
+			}
+			flat = append(flat, s)
+		}
+	}
+	flatten(args)
+
+	var (
+		out     []Selector
+		classes []Class
+		native  []*class
+		groups  = make(map[int][]Selector) // of Causes selectors, by position in out
+	)
+	for _, s := range flat {
+		if cs := constituents(classOf(s)); len(cs) == 1 {
+			if containsClass(native, cs[0]) {
+				continue
+			}
+			if len(classes) == 0 {
+				out = append(out, nil) // placeholder for the classes
+			}
+			native, classes = append(native, cs[0]), append(classes, classOf(s))
+			continue
+		}
+
+		if c := causesOf(s); c != nil {
+			if i, ok := groupOf(out, groups, c); ok {
+				groups[i] = append(groups[i], s)
+				continue
+			}
+			groups[len(out)] = []Selector{s}
+		}
+		out = append(out, s)
+	}
+
+	for i, s := range out {
+		switch {
+		case s == nil && len(classes) == 1:
+			out[i] = classes[0]
+		case s == nil && op == "and":
+			out[i] = AllOf(classes...)
+		case s == nil:
+			out[i] = AnyOf(classes...)
+		case len(groups[i]) > 1:
+			out[i] = fuseCauses(op == "and", groups[i])
+		}
+	}
+
+	if op == "and" {
+		return And(out...)
+	}
+	return Or(out...)
+}
+
+// classOf returns sel as a class, if it is one.
+func classOf(sel Selector) Class {
+	c, _ := sel.(Class)
+	return c
+}
+
+// containsClass reports whether cls is one of cs. Only identical classes
+// are considered duplicates; classes related by an alias or identity still
+// match different errors.
+func containsClass(cs []*class, cls *class) bool {
+	for _, c := range cs {
+		if c == cls {
+			return true
+		}
+	}
+	return false
+}
+
+// causesOf returns the Causes selector sel was built from, if any.
+func causesOf(sel Selector) *causesSelector {
+	if n, ok := sel.(*node); ok && n.op == "" {
+		sel = n.Selector
+	}
+	c, _ := sel.(*causesSelector)
+	return c
+}
+
+// groupOf finds the position of a group of Causes selectors that c can be
+// fused with.
+func groupOf(out []Selector, groups map[int][]Selector, c *causesSelector) (int, bool) {
+	for i := range out {
+		g := groups[i]
+		if len(g) > 0 && len(g) < 64 && sameConfig(causesOf(g[0]).cfg, c.cfg) {
+			return i, true
+		}
+	}
+	return 0, false
+}
+
+// sameConfig reports whether two traversal configurations are known to
+// traverse identically. Configurations holding functions never are.
+func sameConfig(a, b *traverseConfig) bool {
+	return a == b || reflect.DeepEqual(*a, *b)
+}
+
+// fuseCauses returns a selector that decides every one of the Causes
+// selectors ss in a single traversal. If all is set, it matches when each of
+// them would; otherwise, when any of them would.
+func fuseCauses(all bool, ss []Selector) Selector {
+	cs := make([]*causesSelector, len(ss))
+	for i, s := range ss {
+		cs[i] = causesOf(s)
+	}
+
+	full := ^uint64(0) >> (64 - len(cs))
+	cfg := cs[0].cfg
+	return compose("fused", SelectorFunc(func(err error) (bool, error) {
+		var seen uint64
+		cfg.walk(err, func(f frame) (bool, bool) {
+			for i, c := range cs {
+				if seen&(1<<uint(i)) == 0 && c.f(f.err) {
+					seen |= 1 << uint(i)
+				}
+			}
+			return all && seen == full || !all && seen != 0, true
+		})
+
+		if all && seen == full || !all && seen != 0 {
+			return true, err
+		}
+		return false, nil
+	}), ss...)
+}
@@ -0,0 +1,63 @@
+package errsel
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCompile(t *testing.T) {
+	a, b, c := Named("a"), Named("b"), Named("c")
+	var calls int
+	call := Call(func(error) { calls++ }, a)
+
+	sels := []Selector{
+		Or(a, Or(b, a), Error(io.EOF), Is(context.Canceled)),
+		And(a, And(Bind(b, c), a), Not(Not(Error(io.EOF))), Type(&fmtErr{})),
+		And(Error(io.EOF), Error(io.EOF, Lens(1)), Is(io.EOF)),
+		Or(Grep("x"), Not(a), call),
+		Not(Not(And(a, b))),
+		Or(Named("x"), Named("y", Alias("x"))),
+	}
+	errs := []error{
+		nil,
+		io.EOF,
+		a.Lift(b.Lift(c.Lift(io.EOF))),
+		a.Lift(fmt.Errorf("x: %w", Bind(b, c).Lift(io.EOF))),
+		b.Lift(context.Canceled),
+		errors.Wrap(a.New("x"), "y"),
+		a.Lift(b.Lift(c.Lift(&fmtErr{}))),
+		a.Lift(errors.Wrap(&fmtErr{io.EOF}, "x")),
+		Named("x").New("x"),
+		Named("y").New("y"),
+	}
+
+	for _, sel := range sels {
+		compiled := Compile(sel)
+		for _, err := range errs {
+			calls = 0
+			want := sel.In(err)
+			wantCalls := calls
+
+			calls = 0
+			assert.Equal(t, want, compiled.In(err), "%s on %v", Describe(sel), err)
+			assert.Equal(t, wantCalls, calls)
+		}
+	}
+
+	assert.Equal(t, "or(anyof(a, b), fused(error(\"EOF\"), is(\"context canceled\")))", Describe(Compile(sels[0])))
+	assert.Equal(t, "and(allof(a, b, c), fused(error(\"EOF\"), type(*errsel.fmtErr)))", Describe(Compile(sels[1])))
+	assert.Equal(t, "and(fused(error(\"EOF\"), is(\"EOF\")), error(\"EOF\"))", Describe(Compile(sels[2])))
+	assert.Equal(t, "and(allof(a, b))", Describe(Compile(sels[4])))
+	assert.Equal(t, "or(anyof(x, y))", Describe(Compile(sels[5])))
+	assert.Equal(t, a, Compile(a))
+}
+
+type fmtErr struct{ err error }
+
+func (f *fmtErr) Error() string { return "fmt" }
+func (f *fmtErr) Unwrap() error { return f.err }
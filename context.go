@@ -0,0 +1,202 @@
+package errsel
+
+import (
+	"context"
+	"time"
+)
+
+// ContextSelector is a selector whose traversal can be cancelled, and can
+// carry deadlines and values to the selectors it is composed from. This is
+// useful for slow selectors, such as those that hit caches or make RPCs
+// from within a Call hook.
+//
+// Traverse on a ContextSelector is equivalent to TraverseContext with
+// context.Background().
+type ContextSelector interface {
+	Selector
+	TraverseContext(ctx context.Context, err error) (bool, error)
+}
+
+var _ ContextSelector = new(ContextSelectorFunc)
+
+// ContextSelectorFunc is a context-aware traversal function.
+type ContextSelectorFunc func(context.Context, error) (bool, error)
+
+func (f ContextSelectorFunc) TraverseContext(ctx context.Context, err error) (bool, error) {
+	return f(ctx, err)
+}
+
+func (f ContextSelectorFunc) Traverse(err error) (bool, error) {
+	return f(context.Background(), err)
+}
+
+func (f ContextSelectorFunc) In(err error) bool {
+	ok, _ := f.Traverse(err)
+	return ok
+}
+
+func (f ContextSelectorFunc) Is(err error) error {
+	_, er := f.Traverse(err)
+	return er
+}
+
+func (f ContextSelectorFunc) Query(err error) (error, bool) {
+	return queryOf(f, err)
+}
+
+// TraverseContext traverses err with s, passing ctx along if s is a
+// ContextSelector. If ctx is already done, s is not evaluated, and no match
+// is reported.
+func TraverseContext(ctx context.Context, s Selector, err error) (bool, error) {
+	if ctx.Err() != nil {
+		return false, nil
+	}
+	if cs, ok := s.(ContextSelector); ok {
+		return cs.TraverseContext(ctx, err)
+	}
+	return s.Traverse(err)
+}
+
+// ctxNode is a context-aware composition of selectors.
+type ctxNode struct {
+	*node
+	f ContextSelectorFunc
+}
+
+func composeContext(op string, f ContextSelectorFunc, args ...Selector) ContextSelector {
+	return &ctxNode{
+		node: compose(op, f, args...).(*node),
+		f:    f,
+	}
+}
+
+func (n *ctxNode) TraverseContext(ctx context.Context, err error) (bool, error) {
+	return n.f(ctx, err)
+}
+
+// AndContext behaves like And, except that ctx is passed along to every
+// input selector, and evaluation stops with no match as soon as ctx is
+// done.
+func AndContext(ss ...Selector) ContextSelector {
+	return composeContext("and", func(ctx context.Context, err error) (bool, error) {
+		accum := true
+		for _, s := range ss {
+			ok, _ := TraverseContext(ctx, s, err)
+			accum = accum && ok
+		}
+		if ctx.Err() != nil || !accum {
+			return false, nil
+		}
+		return true, err
+	}, ss...)
+}
+
+// OrContext behaves like Or, except that ctx is passed along to every input
+// selector, and evaluation stops with no match as soon as ctx is done.
+func OrContext(ss ...Selector) ContextSelector {
+	return composeContext("or", func(ctx context.Context, err error) (bool, error) {
+		var accum bool
+		for _, s := range ss {
+			ok, _ := TraverseContext(ctx, s, err)
+			accum = accum || ok
+		}
+		if ctx.Err() != nil || !accum {
+			return false, nil
+		}
+		return true, err
+	}, ss...)
+}
+
+// CallContext behaves like Call, except that ctx is passed along to s and
+// to f. If ctx is done before s matches, f is not called.
+func CallContext(f func(context.Context, error), s Selector) ContextSelector {
+	return composeContext("call", func(ctx context.Context, err error) (bool, error) {
+		ok, er := TraverseContext(ctx, s, err)
+		if !ok || ctx.Err() != nil {
+			return false, nil
+		}
+		f(ctx, er)
+		return true, err
+	}, s)
+}
+
+// WithTimeout returns a selector that bounds how long s may take to
+// traverse an error. If s hasn't finished within d, no match is reported;
+// if s is a ContextSelector, its context is cancelled.
+//
+// s keeps running in the background after the timeout unless it respects
+// cancellation, so any side effects it carries may still fire late.
+func WithTimeout(d time.Duration, s Selector) ContextSelector {
+	sel := composeContext("timeout", func(ctx context.Context, err error) (bool, error) {
+		ctx, cancel := context.WithTimeout(ctx, d)
+		defer cancel()
+
+		type result struct {
+			ok bool
+			er error
+			p  *panicked
+		}
+		done := make(chan result, 1)
+		go func() {
+			var r result
+			defer func() { done <- r }()
+			defer recoverInto(&r.p)
+			r.ok, r.er = TraverseContext(ctx, s, err)
+		}()
+
+		select {
+		case r := <-done:
+			r.p.raise()
+			return r.ok, r.er
+		case <-ctx.Done():
+			return false, nil
+		}
+	}, s)
+	sel.(*ctxNode).desc = d.String()
+	return sel
+}
+
+// panicked is a panic recovered in a goroutine that traverses an error on
+// behalf of another, which raises it again, so that Safe can recover it.
+type panicked struct {
+	value interface{}
+}
+
+// recoverInto recovers a panic into *p. It must be deferred directly.
+func recoverInto(p **panicked) {
+	if v := recover(); v != nil {
+		*p = &panicked{v}
+	}
+}
+
+// raise panics again with the recovered value, if there is one.
+func (p *panicked) raise() {
+	if p != nil {
+		panic(p.value)
+	}
+}
+
+// Safe returns a selector that recovers from any panic while s traverses
+// an error, and reports no match instead. One buggy selector will then not
+// take down the whole request path. Panics in selectors that traverse in
+// goroutines of their own, such as WithTimeout and AndC, are recovered as
+// well, unless they happen after the result has been decided.
+func Safe(s Selector) ContextSelector {
+	return SafeReport(nil, s)
+}
+
+// SafeReport behaves like Safe, except that f is called with the error
+// being traversed and the recovered value whenever s panics.
+func SafeReport(f func(err error, recovered interface{}), s Selector) ContextSelector {
+	return composeContext("safe", func(ctx context.Context, err error) (ok bool, er error) {
+		defer func() {
+			if p := recover(); p != nil {
+				ok, er = false, nil
+				if f != nil {
+					f(err, p)
+				}
+			}
+		}()
+		return TraverseContext(ctx, s, err)
+	}, s)
+}
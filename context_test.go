@@ -0,0 +1,93 @@
+package errsel
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type ctxKey struct{}
+
+func TestContextSelector(t *testing.T) {
+	var seen []interface{}
+	record := func(ctx context.Context, err error) {
+		seen = append(seen, ctx.Value(ctxKey{}))
+	}
+
+	sel := AndContext(okayStuff, OrContext(Grep("nope"), CallContext(record, badStuff)))
+	ctx := context.WithValue(context.Background(), ctxKey{}, "value")
+
+	ok, er := sel.TraverseContext(ctx, ErrSomeErr)
+	assert.True(t, ok)
+	assert.Equal(t, ErrSomeErr, er)
+	assert.Equal(t, []interface{}{"value"}, seen)
+
+	assert.True(t, sel.In(ErrSomeErr))
+	assert.Equal(t, []interface{}{"value", nil}, seen)
+
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	ok, er = sel.TraverseContext(cancelled, ErrSomeErr)
+	assert.False(t, ok)
+	assert.Nil(t, er)
+	assert.Len(t, seen, 2)
+
+	ok, _ = TraverseContext(cancelled, okayStuff, ErrSomeErr)
+	assert.False(t, ok)
+
+	assert.Equal(t, "and(anonymous, or(grep(\"nope\"), call(anonymous)))", Describe(sel))
+	assert.True(t, Explain(sel, ErrSomeErr).Matched)
+}
+
+func TestWithTimeout(t *testing.T) {
+	blocking := ContextSelectorFunc(func(ctx context.Context, err error) (bool, error) {
+		<-ctx.Done()
+		return true, err
+	})
+
+	ok, er := WithTimeout(time.Millisecond, blocking).Traverse(ErrSomeErr)
+	assert.False(t, ok)
+	assert.Nil(t, er)
+
+	ok, er = WithTimeout(time.Second, okayStuff).Traverse(ErrSomeErr)
+	assert.True(t, ok)
+	assert.Equal(t, ErrInter, er)
+
+	ok, _ = WithTimeout(time.Millisecond, slow(time.Second, okayStuff)).Traverse(ErrSomeErr)
+	assert.False(t, ok)
+
+	assert.Equal(t, "timeout(1s, anonymous)", Describe(WithTimeout(time.Second, okayStuff)))
+}
+
+func TestSafe(t *testing.T) {
+	boom := Root(func(err error) bool {
+		panic("boom")
+	})
+
+	assert.False(t, Safe(boom).In(ErrSomeErr))
+	assert.False(t, Safe(Grep("x")).In(nil))
+
+	var reported []interface{}
+	sel := SafeReport(func(err error, p interface{}) {
+		assert.Equal(t, ErrSomeErr, err)
+		reported = append(reported, p)
+	}, Or(boom, okayStuff))
+
+	assert.False(t, sel.In(ErrSomeErr))
+	assert.Equal(t, []interface{}{"boom"}, reported)
+
+	ok, er := Safe(okayStuff).Traverse(ErrSomeErr)
+	assert.True(t, ok)
+	assert.Equal(t, ErrInter, er)
+
+	// panics in goroutines of their own are raised where Safe recovers them
+	reported = nil
+	for _, s := range []Selector{WithTimeout(time.Second, boom), AndC(okayStuff, boom), OrC(boom)} {
+		assert.False(t, SafeReport(func(_ error, p interface{}) {
+			reported = append(reported, p)
+		}, s).In(ErrSomeErr), Describe(s))
+	}
+	assert.Equal(t, []interface{}{"boom", "boom", "boom"}, reported)
+}
@@ -0,0 +1,118 @@
+package errsel
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// Coverage records which selectors were exercised while it was collecting,
+// typically during a test run, to find routing rules that no error reaches
+// anymore.
+//
+//    var cov = errsel.NewCoverage()
+//
+//    var routes = []struct {
+//        sel    errsel.Selector
+//        status int
+//    }{
+//        {cov.Track(notFound), http.StatusNotFound},
+//        {cov.Track(errsel.Or(conflict, deadlock)), http.StatusConflict},
+//    }
+//
+//    func TestMain(m *testing.M) {
+//        code := m.Run()
+//        for _, desc := range cov.Unmatched() {
+//            fmt.Printf("route never matched: %s\n", desc)
+//        }
+//        os.Exit(code)
+//    }
+//
+// The zero value is an empty collector ready to use.
+type Coverage struct {
+	mu      sync.Mutex
+	entries []*coverEntry
+}
+
+type coverEntry struct {
+	desc           string
+	evals, matches atomic.Int64
+}
+
+// CoverageEntry describes how often a tracked selector was exercised.
+type CoverageEntry struct {
+	Selector  string // description of the selector
+	Evaluated int64  // number of errors the selector inspected
+	Matched   int64  // number of errors the selector matched
+}
+
+// NewCoverage returns an empty coverage collector.
+func NewCoverage() *Coverage {
+	return new(Coverage)
+}
+
+// Track returns a selector that behaves exactly like s, and that has the
+// same description, but that records each evaluation and match of s in c.
+// Every call to Track registers a separate entry, even for selectors with
+// the same description.
+func (c *Coverage) Track(s Selector) Selector {
+	e := &coverEntry{desc: Describe(s)}
+
+	c.mu.Lock()
+	c.entries = append(c.entries, e)
+	c.mu.Unlock()
+
+	return describe(e.desc, SelectorFunc(func(err error) (bool, error) {
+		e.evals.Add(1)
+		ok, er := s.Traverse(err)
+		if ok {
+			e.matches.Add(1)
+		}
+		return ok, er
+	}))
+}
+
+// Entries returns an entry for every tracked selector, in the order they
+// were tracked.
+func (c *Coverage) Entries() []CoverageEntry {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	out := make([]CoverageEntry, len(c.entries))
+	for i, e := range c.entries {
+		out[i] = CoverageEntry{
+			Selector:  e.desc,
+			Evaluated: e.evals.Load(),
+			Matched:   e.matches.Load(),
+		}
+	}
+	return out
+}
+
+// Unmatched returns the descriptions of tracked selectors that have never
+// matched, in the order they were tracked.
+func (c *Coverage) Unmatched() []string {
+	var out []string
+	for _, e := range c.Entries() {
+		if e.Matched == 0 {
+			out = append(out, e.Selector)
+		}
+	}
+	return out
+}
+
+// TestingT is the subset of *testing.T used by Coverage.
+type TestingT interface {
+	Helper()
+	Errorf(format string, args ...interface{})
+}
+
+// AssertMatched reports an error on t for every tracked selector that has
+// never matched, and returns whether all of them have.
+func (c *Coverage) AssertMatched(t TestingT) bool {
+	t.Helper()
+	unmatched := c.Unmatched()
+	for _, desc := range unmatched {
+		t.Errorf("errsel: selector %s never matched", desc)
+	}
+	return len(unmatched) == 0
+}
@@ -0,0 +1,50 @@
+package errsel
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type recordT struct {
+	errs []string
+}
+
+func (t *recordT) Helper() {}
+
+func (t *recordT) Errorf(format string, args ...interface{}) {
+	t.errs = append(t.errs, fmt.Sprintf(format, args...))
+}
+
+func TestCoverage(t *testing.T) {
+	a, b := Named("cov.a"), Named("cov.b")
+	cov := NewCoverage()
+
+	routes := []Selector{cov.Track(a), cov.Track(Or(a, b)), cov.Track(b)}
+	assert.Equal(t, "or(cov.a, cov.b)", Describe(routes[1]))
+
+	route := func(err error) {
+		for _, r := range routes {
+			if r.In(err) {
+				return
+			}
+		}
+	}
+	route(a.New("x"))
+	route(a.New("x"))
+	route(b.New("x"))
+
+	assert.Equal(t, []CoverageEntry{
+		{Selector: "cov.a", Evaluated: 3, Matched: 2},
+		{Selector: "or(cov.a, cov.b)", Evaluated: 1, Matched: 1},
+		{Selector: "cov.b", Evaluated: 0, Matched: 0},
+	}, cov.Entries())
+	assert.Equal(t, []string{"cov.b"}, cov.Unmatched())
+
+	rt := new(recordT)
+	assert.False(t, cov.AssertMatched(rt))
+	assert.Equal(t, []string{"errsel: selector cov.b never matched"}, rt.errs)
+
+	assert.True(t, NewCoverage().AssertMatched(rt))
+}
@@ -0,0 +1,48 @@
+package errsel
+
+import "sync"
+
+type deprecation struct {
+	replacement *class
+	once        sync.Once
+	f           func(deprecated, replacement Class)
+}
+
+// Deprecated marks a class as deprecated in favor of replacement, to retire
+// it safely. During the migration, the deprecated class and its replacement
+// match each other as selectors, so errors lifted into either are selected
+// by both.
+//
+// The first time an error is lifted into the deprecated class, warn is
+// called with both classes, so that remaining uses can be found. It may be
+// nil.
+//
+//    var NotFound = errsel.Named("not_found")
+//
+//    var Missing = errsel.Named("missing", errsel.Deprecated(NotFound, func(old, new errsel.Class) {
+//        log.Printf("%v is deprecated, use %v", old, new)
+//    }))
+//
+// The replacement must be a class constructed by this package, such as with
+// Named; other classes can't be linked, and only warn will apply.
+func Deprecated(replacement Class, warn func(deprecated, replacement Class)) ClassOption {
+	return ClassOption(func(e *class) {
+		e.dep = &deprecation{f: warn}
+		if c, ok := replacement.(*errClass); ok && c.cls != nil {
+			e.dep.replacement = c.cls
+		}
+	})
+}
+
+func (d *deprecation) warn(e *class) {
+	if d.f == nil {
+		return
+	}
+	d.once.Do(func() {
+		var replacement Class
+		if d.replacement != nil {
+			replacement = d.replacement.self
+		}
+		d.f(e.self, replacement)
+	})
+}
@@ -0,0 +1,67 @@
+// Package errseljson encodes classed errors as JSON, and decodes them back
+// into chains of classed errors, so that selectors on the client side of an
+// internal API behave as they would in-process.
+//
+//    // server
+//    body, _ := errseljson.Encode(err)
+//
+//    // client
+//    err := errseljson.Decode(body)
+//    if conflict.In(err) {
+//        // retry
+//    }
+//
+// Errors are encoded as an errsel.ChainSnapshot, and decoded with
+// errsel.Registry.Restore. Classes are resolved by name with a registry, so
+// that decoded errors are lifted into the same classes, with the same
+// options, as the errors that were encoded; classes that aren't registered
+// are decoded as named classes, which match any class of the same name.
+// Anonymous classes can't be resolved, and are dropped.
+package errseljson
+
+import (
+	"encoding/json"
+
+	"github.com/nytopop/errsel"
+	"github.com/pkg/errors"
+)
+
+// Encode encodes err's context chain as JSON, including any errors hidden
+// beneath a shadowing class.
+func Encode(err error) ([]byte, error) {
+	return json.Marshal(errsel.Snapshot(err))
+}
+
+// Decode decodes an error encoded by Encode, resolving classes with
+// errsel.DefaultRegistry. It returns nil if data encodes a nil error.
+func Decode(data []byte) error {
+	return Decoder{}.Decode(data)
+}
+
+// Decoder decodes errors encoded by Encode.
+type Decoder struct {
+	// Registry resolves classes by name. If nil, errsel.DefaultRegistry is
+	// used.
+	Registry *errsel.Registry
+}
+
+// Decode decodes an error encoded by Encode. It returns nil if data encodes
+// a nil error. If data isn't a valid encoding, the returned error wraps the
+// json decoding error, and matches no class.
+func (d Decoder) Decode(data []byte) error {
+	var snap errsel.ChainSnapshot
+	if err := json.Unmarshal(data, &snap); err != nil {
+		return errors.Wrap(err, "errseljson: decode")
+	}
+	return d.FromSnapshot(snap)
+}
+
+// FromSnapshot reconstructs the error captured by snap; see
+// errsel.Registry.Restore. It returns nil if snap holds no frames.
+func (d Decoder) FromSnapshot(snap errsel.ChainSnapshot) error {
+	r := d.Registry
+	if r == nil {
+		r = errsel.DefaultRegistry
+	}
+	return r.Restore(snap)
+}
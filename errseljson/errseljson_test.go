@@ -0,0 +1,105 @@
+package errseljson
+
+import (
+	stderrors "errors"
+	"regexp"
+	"testing"
+
+	"github.com/nytopop/errsel"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func roundTrip(t *testing.T, d Decoder, err error) error {
+	data, encErr := Encode(err)
+	assert.NoError(t, encErr)
+	return d.Decode(data)
+}
+
+func TestRoundTrip(t *testing.T) {
+	r := errsel.NewRegistry()
+	conflict := r.Named("json.conflict", errsel.Code(409))
+	internal := errsel.NamedShadow("json.internal")
+	database := errsel.Named("json.database")
+
+	err := errors.WithMessage(conflict.Lift(internal.Wrap(database.New("btree"), "query")), "update")
+	got := roundTrip(t, Decoder{Registry: r}, err)
+
+	assert.Equal(t, err.Error(), got.Error())
+	assert.True(t, conflict.In(got))
+	assert.True(t, internal.In(got))
+	assert.False(t, database.In(got))
+	assert.True(t, errsel.Classes(database.In, errsel.IgnoreShadow()).In(got))
+
+	code, ok := errsel.CodeOf(got)
+	assert.True(t, ok)
+	assert.Equal(t, 409, code)
+
+	var e *errsel.SnapshotError
+	assert.True(t, stderrors.As(got, &e))
+	assert.Equal(t, "update", e.Message)
+	assert.Equal(t, "*errors.withMessage", e.Type)
+
+	var root error
+	for e := range errsel.Chain(got) {
+		root = e
+	}
+	assert.IsType(t, &errsel.SnapshotError{}, root)
+	assert.Equal(t, "btree", root.(*errsel.SnapshotError).Message)
+	assert.NotEmpty(t, root.(*errsel.SnapshotError).Stack)
+
+	anon := errsel.Anonymous(errsel.Identity(errsel.NewIdentity()))
+	assert.True(t, anon.In(roundTrip(t, Decoder{}, anon.New("x"))))
+}
+
+func TestRoundTripBranches(t *testing.T) {
+	a, b, c := errsel.Named("json.a"), errsel.Named("json.b"), errsel.Named("json.c")
+
+	err := stderrors.Join(errsel.Bind(a, b).New("x"), c.New("y"), errsel.Anonymous().New("z"))
+	got := roundTrip(t, Decoder{}, err)
+
+	assert.Equal(t, err.Error(), got.Error())
+	assert.True(t, errsel.AllOf(a, b, c).In(got))
+	assert.Len(t, errsel.ClassesOf(got), 3)
+}
+
+func TestEncodeScrubbed(t *testing.T) {
+	tokens := errsel.ScrubRegexp(regexp.MustCompile(`token=\w+`), "token=***")
+	api := errsel.Named("json.api", errsel.Scrub(tokens))
+
+	err := errors.WithMessage(api.Wrap(errors.New("bad token=SECRET123"), "login"), "handler")
+	data, jerr := Encode(err)
+	assert.NoError(t, jerr)
+	assert.NotContains(t, string(data), "SECRET123")
+	assert.Equal(t, err.Error(), roundTrip(t, Decoder{}, err).Error())
+}
+
+func TestRoundTripStable(t *testing.T) {
+	var lifts int
+	r := errsel.NewRegistry()
+	conflict := r.Named("json.stable", errsel.DefaultMessage("resource version conflict"),
+		errsel.Intercept(func(_ errsel.Class, err error) error {
+			lifts++
+			return err
+		}))
+
+	err := conflict.Lift(errors.New("stale"))
+	got := roundTrip(t, Decoder{Registry: r}, err)
+	assert.Equal(t, err.Error(), got.Error())
+	assert.Equal(t, err.Error(), roundTrip(t, Decoder{Registry: r}, got).Error())
+	assert.True(t, conflict.In(got))
+	assert.Equal(t, 1, lifts)
+
+	shadow := errsel.NamedShadow("json.shadow")
+	r.MustRegister("json.shadow", shadow)
+	err = shadow.Lift(conflict.New("x"))
+	assert.Equal(t, err.Error(), roundTrip(t, Decoder{Registry: r}, err).Error())
+}
+
+func TestDecodeEmpty(t *testing.T) {
+	assert.Nil(t, roundTrip(t, Decoder{}, nil))
+
+	err := Decode([]byte("{"))
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "errseljson: decode")
+}
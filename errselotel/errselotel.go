@@ -0,0 +1,126 @@
+// Package errselotel records error classifications on OpenTelemetry spans.
+//
+// It doesn't depend on OpenTelemetry; spans are reached through a small
+// adapter, installed once with SetSpans:
+//
+//    type otelSpan struct{ trace.Span }
+//
+//    func (s otelSpan) AddEvent(name string, attrs map[string]string) {
+//        s.Span.AddEvent(name, trace.WithAttributes(kvs(attrs)...))
+//    }
+//
+//    func (s otelSpan) SetAttributes(attrs map[string]string) {
+//        s.Span.SetAttributes(kvs(attrs)...)
+//    }
+//
+//    errselotel.SetSpans(func(ctx context.Context) errselotel.Span {
+//        return otelSpan{trace.SpanFromContext(ctx)}
+//    })
+//
+// Until then, nothing is recorded.
+package errselotel
+
+import (
+	"context"
+	"strings"
+	"sync/atomic"
+
+	"github.com/nytopop/errsel"
+)
+
+// Attribute keys recorded by this package.
+const (
+	// KeySelector is the description of the selector that matched an
+	// error.
+	KeySelector = "errsel.selector"
+
+	// KeyClass is the description of the class an error was lifted into.
+	KeyClass = "errsel.class"
+
+	// KeyClasses lists the classes annotating an error's context chain,
+	// from the outermost inward, separated by commas.
+	KeyClasses = "errsel.classes"
+
+	// KeyMessage is the message of an error.
+	KeyMessage = "exception.message"
+)
+
+// EventMatch is the name of events recorded by RecordOn.
+const EventMatch = "errsel.match"
+
+// Span is the part of an OpenTelemetry span used by this package.
+type Span interface {
+	AddEvent(name string, attrs map[string]string)
+	SetAttributes(attrs map[string]string)
+}
+
+type spansBox struct {
+	f func(context.Context) Span
+}
+
+var spans atomic.Pointer[spansBox]
+
+// SetSpans sets the function used to find the span of a context. It should
+// return nil if the context has no span. It is safe for concurrent use, but
+// should typically be called once during program initialization.
+func SetSpans(f func(context.Context) Span) {
+	spans.Store(&spansBox{f})
+}
+
+func spanFrom(ctx context.Context) Span {
+	if b := spans.Load(); b != nil && b.f != nil {
+		return b.f(ctx)
+	}
+	return nil
+}
+
+// RecordOn returns a selector that behaves like sel, but when traversed
+// with errsel.TraverseContext, records an EventMatch event on the span of
+// the context every time sel matches. The event carries the description of
+// sel, the classes of the matched error and its message.
+//
+//    var retryable = errselotel.RecordOn(errsel.Or(timeout, conflict))
+//
+//    if ok, _ := errsel.TraverseContext(ctx, retryable, err); ok {
+//        // retry
+//    }
+func RecordOn(sel errsel.Selector) errsel.ContextSelector {
+	desc := errsel.Describe(sel)
+	return errsel.ContextSelectorFunc(func(ctx context.Context, err error) (bool, error) {
+		ok, er := errsel.TraverseContext(ctx, sel, err)
+		if !ok {
+			return false, nil
+		}
+		if span := spanFrom(ctx); span != nil {
+			span.AddEvent(EventMatch, map[string]string{
+				KeySelector: desc,
+				KeyClasses:  classes(er),
+				KeyMessage:  er.Error(),
+			})
+		}
+		return true, er
+	})
+}
+
+// Lifter returns a lifter that lifts errors into cls, and sets KeyClass on
+// the span of ctx to the description of cls.
+//
+//    return errselotel.Lifter(ctx, conflict).Wrap(err, "update user")
+func Lifter(ctx context.Context, cls errsel.Class) errsel.Lifter {
+	desc := errsel.Describe(cls)
+	return errsel.LifterFunc(func(err error) error {
+		err = cls.Lift(err)
+		if span := spanFrom(ctx); span != nil && err != nil {
+			span.SetAttributes(map[string]string{KeyClass: desc})
+		}
+		return err
+	})
+}
+
+func classes(err error) string {
+	var names []string
+	for _, cls := range errsel.ClassesOf(err) {
+		names = append(names, errsel.Describe(cls))
+	}
+	return strings.Join(names, ",")
+}
@@ -0,0 +1,67 @@
+package errselotel
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/nytopop/errsel"
+	"github.com/stretchr/testify/assert"
+)
+
+type span struct {
+	events []map[string]string
+	attrs  map[string]string
+}
+
+func (s *span) AddEvent(name string, attrs map[string]string) {
+	attrs["name"] = name
+	s.events = append(s.events, attrs)
+}
+
+func (s *span) SetAttributes(attrs map[string]string) {
+	for k, v := range attrs {
+		s.attrs[k] = v
+	}
+}
+
+type spanKey struct{}
+
+func TestRecordOn(t *testing.T) {
+	a, b := errsel.Named("a"), errsel.Named("b")
+	sel := RecordOn(errsel.Or(a, b))
+	err := a.Lift(b.New("x"))
+
+	// nothing is recorded until spans are set
+	assert.True(t, sel.In(err))
+
+	SetSpans(func(ctx context.Context) Span {
+		s, _ := ctx.Value(spanKey{}).(*span)
+		if s == nil {
+			return nil
+		}
+		return s
+	})
+	defer SetSpans(nil)
+
+	s := &span{attrs: map[string]string{}}
+	ctx := context.WithValue(context.Background(), spanKey{}, s)
+
+	ok, _ := errsel.TraverseContext(ctx, sel, err)
+	assert.True(t, ok)
+	ok, _ = errsel.TraverseContext(ctx, sel, errors.New("x"))
+	assert.False(t, ok)
+	ok, _ = errsel.TraverseContext(context.Background(), sel, err)
+	assert.True(t, ok)
+
+	assert.Equal(t, []map[string]string{{
+		"name":      EventMatch,
+		KeySelector: "or(a, b)",
+		KeyClasses:  "a,b",
+		KeyMessage:  "a{ b{ x } }",
+	}}, s.events)
+
+	err = Lifter(ctx, a).Wrap(errors.New("x"), "y")
+	assert.Equal(t, "a{ y: x }", err.Error())
+	assert.Equal(t, "a", s.attrs[KeyClass])
+}
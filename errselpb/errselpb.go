@@ -0,0 +1,153 @@
+// Package errselpb encodes classed errors as protocol buffers, so that they
+// can be embedded in gRPC error details and message payloads, and decodes
+// them back into chains of classed errors with full selector fidelity.
+//
+// Messages follow the schema in errsel.proto. This package doesn't depend
+// on a protobuf runtime; Chain implements the wire format itself, so its
+// encoding can be decoded by code generated from the schema, and the
+// other way around:
+//
+//    // server
+//    data, _ := errselpb.To(err).Marshal()
+//
+//    // client
+//    var msg errselpb.Chain
+//    if err := msg.Unmarshal(data); err == nil && conflict.In(errselpb.From(&msg)) {
+//        // retry
+//    }
+//
+// Classes are resolved by name with a registry when decoding; see
+// errsel.Registry.Restore.
+package errselpb
+
+import (
+	"fmt"
+
+	"github.com/nytopop/errsel"
+)
+
+// Chain is an error's context chain.
+type Chain struct {
+	Error  string
+	Frames []*Frame
+}
+
+// Frame is a single intermediate error in a chain; see
+// errsel.FrameSnapshot.
+type Frame struct {
+	Depth           uint32
+	Type            string
+	Message         string
+	Class           string
+	Bound           []string
+	Shadow          bool
+	Shadowed        bool
+	Code            *int64
+	Fields          map[string]string
+	Stack           []*StackFrame
+	Identity        string
+	BoundIdentities []string
+}
+
+// StackFrame is a single frame of a stack trace.
+type StackFrame struct {
+	Function string
+	File     string
+	Line     int64
+}
+
+// To converts err's context chain to a Chain, including any errors hidden
+// beneath a shadowing class. Field values are formatted with fmt.Sprint. It
+// returns nil if err is nil.
+func To(err error) *Chain {
+	if err == nil {
+		return nil
+	}
+
+	snap := errsel.Snapshot(err)
+	msg := &Chain{Error: snap.Error, Frames: make([]*Frame, len(snap.Frames))}
+	for i, f := range snap.Frames {
+		pf := &Frame{
+			Depth:           uint32(f.Depth),
+			Type:            f.Type,
+			Message:         f.Message,
+			Class:           f.Class,
+			Bound:           f.Bound,
+			Shadow:          f.Shadow,
+			Shadowed:        f.Shadowed,
+			Identity:        f.Identity,
+			BoundIdentities: f.BoundIdentities,
+		}
+		if f.Code != nil {
+			code := int64(*f.Code)
+			pf.Code = &code
+		}
+		if len(f.Fields) > 0 {
+			pf.Fields = make(map[string]string, len(f.Fields))
+			for k, v := range f.Fields {
+				pf.Fields[k] = fmt.Sprint(v)
+			}
+		}
+		for _, sf := range f.Stack {
+			pf.Stack = append(pf.Stack, &StackFrame{Function: sf.Function, File: sf.File, Line: int64(sf.Line)})
+		}
+		msg.Frames[i] = pf
+	}
+	return msg
+}
+
+// From reconstructs the error converted by To, resolving classes with
+// errsel.DefaultRegistry. It returns nil if msg is nil or holds no frames.
+func From(msg *Chain) error {
+	return Decoder{}.From(msg)
+}
+
+// Decoder reconstructs errors converted by To.
+type Decoder struct {
+	// Registry resolves classes by name. If nil, errsel.DefaultRegistry is
+	// used.
+	Registry *errsel.Registry
+}
+
+// From reconstructs the error converted by To. It returns nil if msg is nil
+// or holds no frames.
+func (d Decoder) From(msg *Chain) error {
+	if msg == nil {
+		return nil
+	}
+
+	snap := errsel.ChainSnapshot{Error: msg.Error, Frames: make([]errsel.FrameSnapshot, len(msg.Frames))}
+	for i, pf := range msg.Frames {
+		f := errsel.FrameSnapshot{
+			Depth:           uint(pf.Depth),
+			Type:            pf.Type,
+			Message:         pf.Message,
+			Class:           pf.Class,
+			Bound:           pf.Bound,
+			Shadow:          pf.Shadow,
+			Shadowed:        pf.Shadowed,
+			Identity:        pf.Identity,
+			BoundIdentities: pf.BoundIdentities,
+		}
+		if pf.Code != nil {
+			code := int(*pf.Code)
+			f.Code = &code
+		}
+		if len(pf.Fields) > 0 {
+			f.Fields = make(errsel.Fields, len(pf.Fields))
+			for k, v := range pf.Fields {
+				f.Fields[k] = v
+			}
+		}
+		for _, sf := range pf.Stack {
+			f.Stack = append(f.Stack, errsel.StackFrame{Function: sf.Function, File: sf.File, Line: int(sf.Line)})
+		}
+		snap.Frames[i] = f
+	}
+
+	r := d.Registry
+	if r == nil {
+		r = errsel.DefaultRegistry
+	}
+	return r.Restore(snap)
+}
@@ -0,0 +1,123 @@
+package errselpb
+
+import (
+	"testing"
+
+	"github.com/nytopop/errsel"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func roundTrip(t *testing.T, d Decoder, err error) error {
+	data, merr := To(err).Marshal()
+	assert.NoError(t, merr)
+
+	var msg Chain
+	assert.NoError(t, msg.Unmarshal(data))
+	return d.From(&msg)
+}
+
+func TestRoundTrip(t *testing.T) {
+	r := errsel.NewRegistry()
+	conflict := r.Named("pb.conflict", errsel.Code(409))
+	internal, database := errsel.NamedShadow("pb.internal"), errsel.Named("pb.database")
+
+	orig := errors.WithMessage(conflict.WithFields(
+		internal.Wrap(database.New("btree"), "query"), errsel.Fields{"user": 7, "table": "t"},
+	), "update")
+	err := roundTrip(t, Decoder{Registry: r}, orig)
+
+	assert.Equal(t, orig.Error(), err.Error())
+	assert.True(t, errsel.AllOf(conflict, internal).In(err))
+	assert.False(t, database.In(err))
+	assert.True(t, errsel.Classes(database.In, errsel.IgnoreShadow()).In(err))
+	assert.Equal(t, errsel.Fields{"user": "7", "table": "t"}, errsel.FieldsOf(err))
+
+	code, ok := errsel.CodeOf(err)
+	assert.True(t, ok)
+	assert.Equal(t, 409, code)
+
+	var root error
+	for e := range errsel.Chain(err) {
+		root = e
+	}
+	assert.IsType(t, &errsel.SnapshotError{}, root)
+	assert.Equal(t, errsel.StackOf(orig), root.(*errsel.SnapshotError).Stack)
+
+	unregistered := roundTrip(t, Decoder{}, errsel.Named("pb.unregistered", errsel.Code(409)).New("x"))
+	code, ok = errsel.CodeOf(unregistered)
+	assert.True(t, ok)
+	assert.Equal(t, 409, code)
+
+	anon := errsel.Anonymous(errsel.Identity(errsel.NewIdentity()))
+	bound := roundTrip(t, Decoder{}, errsel.Bind(errsel.Named("pb.bound"), anon).New("x"))
+	assert.True(t, errsel.AllOf(errsel.Named("pb.bound"), anon).In(bound))
+	assert.True(t, anon.In(roundTrip(t, Decoder{}, anon.New("x"))))
+
+	assert.Nil(t, To(nil))
+	assert.Nil(t, From(nil))
+}
+
+func TestSensitive(t *testing.T) {
+	database := errsel.Named("pb.sensitive", errsel.Sensitive())
+	err := errors.WithMessage(database.Wrap(errors.New("dial postgres://admin:hunter2@db"), "query"), "handler")
+
+	data, merr := To(err).Marshal()
+	assert.NoError(t, merr)
+	assert.NotContains(t, string(data), "hunter2")
+	assert.Equal(t, err.Error(), roundTrip(t, Decoder{}, err).Error())
+}
+
+func TestWireFormat(t *testing.T) {
+	code := int64(409)
+	msg := &Chain{
+		Error: "x",
+		Frames: []*Frame{{
+			Depth:  1,
+			Class:  "c",
+			Code:   &code,
+			Fields: map[string]string{"k": "v"},
+		}},
+	}
+
+	data, err := msg.Marshal()
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{
+		0x0a, 0x01, 'x', // error
+		0x12, 0x10, // frames
+		0x08, 0x01, // depth
+		0x22, 0x01, 'c', // class
+		0x40, 0x99, 0x03, // code
+		0x4a, 0x06, 0x0a, 0x01, 'k', 0x12, 0x01, 'v', // fields
+	}, data)
+
+	// unknown fields of every wire type are skipped
+	unknown := append([]byte{
+		0x78, 0x05, // 15: varint
+		0x81, 0x01, 1, 2, 3, 4, 5, 6, 7, 8, // 16: fixed64
+		0x8d, 0x01, 1, 2, 3, 4, // 17: fixed32
+		0x92, 0x01, 0x02, 'a', 'b', // 18: bytes
+	}, data...)
+	var got Chain
+	assert.NoError(t, got.Unmarshal(unknown))
+	assert.Equal(t, msg, &got)
+
+	var none *Chain
+	data, err = none.Marshal()
+	assert.NoError(t, err)
+	assert.Empty(t, data)
+	data, err = (&Chain{Frames: []*Frame{nil, {Stack: []*StackFrame{nil}}}}).Marshal()
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{0x12, 0x00, 0x12, 0x02, 0x52, 0x00}, data)
+
+	for _, bad := range [][]byte{
+		{0x0a, 0x05, 'x'}, // truncated
+		{0x08},            // missing varint
+		{0x0a, 0x01},      // missing bytes
+		{0x00},            // field 0
+		{0x0b},            // group
+		{0x08, 0x01},      // error as varint
+	} {
+		assert.True(t, errsel.Error(ErrInvalidWire).In(got.Unmarshal(bad)), "%x", bad)
+	}
+}
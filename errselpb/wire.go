@@ -0,0 +1,306 @@
+package errselpb
+
+import (
+	"encoding/binary"
+	"sort"
+
+	"github.com/pkg/errors"
+)
+
+// ErrInvalidWire is returned when unmarshaling data that isn't a valid
+// protobuf encoding.
+var ErrInvalidWire = errors.New("errselpb: invalid wire format")
+
+// Wire types of the protobuf encoding.
+const (
+	wireVarint  = 0
+	wireFixed64 = 1
+	wireBytes   = 2
+	wireFixed32 = 5
+)
+
+// Marshal encodes c in the protobuf wire format, as described by
+// errsel.proto. Fields are encoded in order of their numbers, and map
+// entries in order of their keys, so the encoding is deterministic. A nil
+// Chain, like an empty one, has an empty encoding.
+func (c *Chain) Marshal() ([]byte, error) {
+	if c == nil {
+		return nil, nil
+	}
+	var b encoder
+	b.string(1, c.Error)
+	for _, f := range c.Frames {
+		b.message(2, f.marshal)
+	}
+	return b, nil
+}
+
+func (f *Frame) marshal(b *encoder) {
+	if f == nil {
+		return
+	}
+	b.uint(1, uint64(f.Depth))
+	b.string(2, f.Type)
+	b.string(3, f.Message)
+	b.string(4, f.Class)
+	for _, name := range f.Bound {
+		b.tag(5, wireBytes)
+		b.bytes([]byte(name))
+	}
+	b.bool(6, f.Shadow)
+	b.bool(7, f.Shadowed)
+	if f.Code != nil {
+		b.tag(8, wireVarint)
+		b.varint(uint64(*f.Code))
+	}
+
+	keys := make([]string, 0, len(f.Fields))
+	for k := range f.Fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		b.message(9, func(e *encoder) {
+			e.string(1, k)
+			e.string(2, f.Fields[k])
+		})
+	}
+
+	for _, sf := range f.Stack {
+		if sf == nil {
+			sf = new(StackFrame)
+		}
+		b.message(10, func(e *encoder) {
+			e.string(1, sf.Function)
+			e.string(2, sf.File)
+			e.uint(3, uint64(sf.Line))
+		})
+	}
+
+	b.string(11, f.Identity)
+	for _, id := range f.BoundIdentities {
+		b.tag(12, wireBytes)
+		b.bytes([]byte(id))
+	}
+}
+
+// encoder appends to a protobuf encoding. Fields holding their zero value
+// are omitted, as proto3 requires.
+type encoder []byte
+
+func (b *encoder) varint(v uint64) {
+	*b = binary.AppendUvarint(*b, v)
+}
+
+func (b *encoder) tag(num int, wire int) {
+	b.varint(uint64(num)<<3 | uint64(wire))
+}
+
+func (b *encoder) bytes(p []byte) {
+	b.varint(uint64(len(p)))
+	*b = append(*b, p...)
+}
+
+func (b *encoder) uint(num int, v uint64) {
+	if v != 0 {
+		b.tag(num, wireVarint)
+		b.varint(v)
+	}
+}
+
+func (b *encoder) bool(num int, v bool) {
+	if v {
+		b.uint(num, 1)
+	}
+}
+
+func (b *encoder) string(num int, s string) {
+	if s != "" {
+		b.tag(num, wireBytes)
+		b.bytes([]byte(s))
+	}
+}
+
+func (b *encoder) message(num int, f func(*encoder)) {
+	var sub encoder
+	f(&sub)
+	b.tag(num, wireBytes)
+	b.bytes(sub)
+}
+
+// Unmarshal decodes a protobuf encoding of a Chain into c, replacing its
+// contents. Unknown fields are skipped. If data isn't a valid encoding, it
+// returns an error matching ErrInvalidWire.
+func (c *Chain) Unmarshal(data []byte) error {
+	*c = Chain{}
+	return decode(data, func(num int, d *decoder) error {
+		switch num {
+		case 1:
+			c.Error = d.string()
+		case 2:
+			f := new(Frame)
+			if err := decode(d.bytes(), f.unmarshal); err != nil {
+				return err
+			}
+			c.Frames = append(c.Frames, f)
+		default:
+			d.skip()
+		}
+		return nil
+	})
+}
+
+func (f *Frame) unmarshal(num int, d *decoder) error {
+	switch num {
+	case 1:
+		f.Depth = uint32(d.varint())
+	case 2:
+		f.Type = d.string()
+	case 3:
+		f.Message = d.string()
+	case 4:
+		f.Class = d.string()
+	case 5:
+		f.Bound = append(f.Bound, d.string())
+	case 6:
+		f.Shadow = d.varint() != 0
+	case 7:
+		f.Shadowed = d.varint() != 0
+	case 8:
+		code := int64(d.varint())
+		f.Code = &code
+	case 9:
+		var k, v string
+		err := decode(d.bytes(), func(num int, d *decoder) error {
+			switch num {
+			case 1:
+				k = d.string()
+			case 2:
+				v = d.string()
+			default:
+				d.skip()
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		if f.Fields == nil {
+			f.Fields = make(map[string]string)
+		}
+		f.Fields[k] = v
+	case 10:
+		sf := new(StackFrame)
+		err := decode(d.bytes(), func(num int, d *decoder) error {
+			switch num {
+			case 1:
+				sf.Function = d.string()
+			case 2:
+				sf.File = d.string()
+			case 3:
+				sf.Line = int64(d.varint())
+			default:
+				d.skip()
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		f.Stack = append(f.Stack, sf)
+	case 11:
+		f.Identity = d.string()
+	case 12:
+		f.BoundIdentities = append(f.BoundIdentities, d.string())
+	default:
+		d.skip()
+	}
+	return nil
+}
+
+// decoder reads a single field of a protobuf encoding. Reading a value of
+// the wrong wire type, or past the end of the data, marks it invalid.
+type decoder struct {
+	data    []byte
+	wire    int
+	invalid bool
+}
+
+// decode calls f with the number of each field in data, and a decoder
+// positioned at its value. f must consume the value.
+func decode(data []byte, f func(num int, d *decoder) error) error {
+	d := decoder{data: data}
+	for len(d.data) > 0 {
+		d.wire = wireVarint
+		tag := d.varint()
+		if d.invalid || tag>>3 == 0 {
+			return errors.Wrap(ErrInvalidWire, "tag")
+		}
+
+		num := int(tag >> 3)
+		d.wire = int(tag & 7)
+		if err := f(num, &d); err != nil {
+			return err
+		}
+		if d.invalid {
+			return errors.Wrapf(ErrInvalidWire, "field %d", num)
+		}
+	}
+	return nil
+}
+
+func (d *decoder) varint() uint64 {
+	if d.wire != wireVarint {
+		d.invalid = true
+		return 0
+	}
+	v, n := binary.Uvarint(d.data)
+	if n <= 0 {
+		d.invalid = true
+		return 0
+	}
+	d.data = d.data[n:]
+	return v
+}
+
+func (d *decoder) bytes() []byte {
+	if d.wire != wireBytes {
+		d.invalid = true
+		return nil
+	}
+	d.wire = wireVarint
+	n := d.varint()
+	if d.invalid || n > uint64(len(d.data)) {
+		d.invalid = true
+		return nil
+	}
+	p := d.data[:n]
+	d.data = d.data[n:]
+	return p
+}
+
+func (d *decoder) string() string {
+	return string(d.bytes())
+}
+
+// skip consumes a value of an unknown field.
+func (d *decoder) skip() {
+	switch d.wire {
+	case wireVarint:
+		d.varint()
+	case wireBytes:
+		d.bytes()
+	case wireFixed64, wireFixed32:
+		n := 8
+		if d.wire == wireFixed32 {
+			n = 4
+		}
+		if len(d.data) < n {
+			d.invalid = true
+			return
+		}
+		d.data = d.data[n:]
+	default:
+		d.invalid = true
+	}
+}
@@ -0,0 +1,52 @@
+// Package errselprom counts error classifications with Prometheus.
+//
+// It doesn't depend on the Prometheus client library; any counter vector
+// whose WithLabelValues method returns something with an Inc method will
+// do, which *prometheus.CounterVec satisfies:
+//
+//    var matches = promauto.NewCounterVec(prometheus.CounterOpts{
+//        Name: "errors_matched_total",
+//    }, []string{"selector"})
+//
+//    var retryable = errselprom.Counted(errsel.Or(timeout, conflict), matches)
+//
+// Vectors are labeled with one label, holding the description of a selector
+// or class as given by errsel.Describe.
+package errselprom
+
+import "github.com/nytopop/errsel"
+
+// Counter is the part of prometheus.Counter used by this package.
+type Counter interface {
+	Inc()
+}
+
+// CounterVec is the part of *prometheus.CounterVec used by this package.
+type CounterVec[C Counter] interface {
+	WithLabelValues(lvs ...string) C
+}
+
+// Counted returns a selector that behaves exactly like sel, but increments
+// the counter of vec labeled with the description of sel every time it
+// matches.
+func Counted[C Counter, V CounterVec[C]](sel errsel.Selector, vec V) errsel.Selector {
+	c := vec.WithLabelValues(errsel.Describe(sel))
+	return errsel.Call(func(error) { c.Inc() }, sel)
+}
+
+// InstrumentClass returns a class option that increments the counter of
+// vec labeled with the description of the class every time an error is
+// lifted into it. Children of the class are counted under their own
+// descriptions.
+//
+//    var lifts = promauto.NewCounterVec(prometheus.CounterOpts{
+//        Name: "errors_lifted_total",
+//    }, []string{"class"})
+//
+//    var conflict = errsel.Named("conflict", errselprom.InstrumentClass(lifts))
+func InstrumentClass[C Counter, V CounterVec[C]](vec V) errsel.ClassOption {
+	return errsel.Intercept(func(cls errsel.Class, err error) error {
+		vec.WithLabelValues(errsel.Describe(cls)).Inc()
+		return err
+	})
+}
@@ -0,0 +1,46 @@
+package errselprom
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/nytopop/errsel"
+	"github.com/stretchr/testify/assert"
+)
+
+type counter struct{ n *int }
+
+func (c counter) Inc() { *c.n++ }
+
+type vec map[string]*int
+
+func (v vec) WithLabelValues(lvs ...string) counter {
+	if v[lvs[0]] == nil {
+		v[lvs[0]] = new(int)
+	}
+	return counter{v[lvs[0]]}
+}
+
+func TestCounted(t *testing.T) {
+	matches := vec{}
+	a, b := errsel.Named("a"), errsel.Named("b")
+	sel := Counted(errsel.Or(a, b), matches)
+
+	assert.True(t, sel.In(a.New("x")))
+	assert.True(t, sel.In(b.New("x")))
+	assert.False(t, sel.In(errors.New("x")))
+	assert.Equal(t, 2, *matches["or(a, b)"])
+}
+
+func TestInstrumentClass(t *testing.T) {
+	lifts := vec{}
+	a := errsel.Named("a", InstrumentClass(lifts))
+	child := a.Child("child")
+
+	_ = a.New("x")
+	_ = a.Wrap(errors.New("x"), "y")
+	_ = child.New("x")
+	assert.Equal(t, 2, *lifts["a"])
+	assert.Equal(t, 1, *lifts["child"])
+	assert.True(t, a.In(child.New("x")))
+}
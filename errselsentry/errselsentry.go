@@ -0,0 +1,56 @@
+// Package errselsentry reports classed errors to Sentry, grouped by class
+// rather than by message.
+//
+// It doesn't depend on the Sentry SDK; any hub with the SDK's WithScope and
+// CaptureException methods will do, which *sentry.Hub satisfies:
+//
+//    var reporter = errselsentry.NewReporter(sentry.CurrentHub())
+//
+//    var unexpected = errsel.ReportOn(errsel.Not(notFound), reporter)
+package errselsentry
+
+import "github.com/nytopop/errsel"
+
+// Tags set on reported events.
+const (
+	// TagClass is the outermost class of a reported error.
+	TagClass = "errsel.class"
+
+	// TagSelector is the selector that matched a reported error.
+	TagSelector = "errsel.selector"
+)
+
+// Scope is the part of *sentry.Scope used by this package.
+type Scope interface {
+	SetFingerprint(fingerprint []string)
+	SetTag(key, value string)
+	SetExtra(key string, value interface{})
+}
+
+// Hub is the part of *sentry.Hub used by this package.
+type Hub[S Scope, ID any] interface {
+	WithScope(f func(scope S))
+	CaptureException(exception error) ID
+}
+
+// NewReporter returns a reporter that captures errors with hub. Errors that
+// are annotated with classes are fingerprinted by their classes, so that
+// Sentry groups them by class; other errors are grouped as Sentry usually
+// would. Fields attached to errors are reported as extra data.
+func NewReporter[S Scope, ID any, H Hub[S, ID]](hub H) errsel.Reporter {
+	return errsel.ReporterFunc(func(err error, meta errsel.ReportMeta) {
+		hub.WithScope(func(scope S) {
+			if len(meta.Classes) > 0 {
+				scope.SetFingerprint(append([]string{"errsel"}, meta.Classes...))
+				scope.SetTag(TagClass, meta.Classes[0])
+			}
+			if meta.Selector != "" {
+				scope.SetTag(TagSelector, meta.Selector)
+			}
+			for k, v := range meta.Fields {
+				scope.SetExtra(k, v)
+			}
+			hub.CaptureException(err)
+		})
+	})
+}
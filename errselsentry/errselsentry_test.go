@@ -0,0 +1,62 @@
+package errselsentry
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/nytopop/errsel"
+	"github.com/stretchr/testify/assert"
+)
+
+type scope struct {
+	fingerprint []string
+	tags        map[string]string
+	extra       map[string]interface{}
+	captured    []error
+}
+
+func (s *scope) SetFingerprint(fingerprint []string)    { s.fingerprint = fingerprint }
+func (s *scope) SetTag(key, value string)               { s.tags[key] = value }
+func (s *scope) SetExtra(key string, value interface{}) { s.extra[key] = value }
+
+type eventID string
+
+type hub struct {
+	scopes []*scope
+	cur    *scope
+}
+
+func (h *hub) WithScope(f func(*scope)) {
+	h.cur = &scope{tags: map[string]string{}, extra: map[string]interface{}{}}
+	h.scopes = append(h.scopes, h.cur)
+	f(h.cur)
+	h.cur = nil
+}
+
+func (h *hub) CaptureException(err error) *eventID {
+	h.cur.captured = append(h.cur.captured, err)
+	id := eventID("id")
+	return &id
+}
+
+func TestNewReporter(t *testing.T) {
+	h := new(hub)
+	a, b := errsel.Named("a"), errsel.Named("b")
+	sel := errsel.ReportOn(errsel.Or(b, errsel.Grep("plain")), NewReporter(h))
+
+	err := a.WithFields(b.New("x"), errsel.Fields{"user": 42})
+	assert.True(t, sel.In(err))
+	assert.True(t, sel.In(errors.New("plain")))
+	assert.False(t, sel.In(errors.New("x")))
+	assert.Len(t, h.scopes, 2)
+
+	s := h.scopes[0]
+	assert.Equal(t, []error{err}, s.captured)
+	assert.Equal(t, []string{"errsel", "a", "b"}, s.fingerprint)
+	assert.Equal(t, map[string]string{TagClass: "a", TagSelector: `or(b, grep("plain"))`}, s.tags)
+	assert.Equal(t, map[string]interface{}{"user": 42}, s.extra)
+
+	s = h.scopes[1]
+	assert.Nil(t, s.fingerprint)
+	assert.Equal(t, map[string]string{TagSelector: `or(b, grep("plain"))`}, s.tags)
+}
@@ -0,0 +1,79 @@
+// Package errselzap maps classed errors into zap fields, so that they are
+// logged with a consistent shape: the error's message, its classes, its root
+// cause and the stack trace nearest to where it originated.
+//
+// It doesn't depend on zap; fields are built with a field constructor,
+// usually zap.Any:
+//
+//    logger.Error("request failed", errselzap.Fields(err, zap.Any)...)
+package errselzap
+
+import "github.com/nytopop/errsel"
+
+// Field keys used by this package.
+const (
+	KeyError   = "error"
+	KeyClasses = "error_classes"
+	KeyCause   = "error_cause"
+	KeyStack   = "error_stack"
+)
+
+// Fields returns fields describing err, built with field. The classes and
+// stack are omitted if err has none.
+func Fields[F any](err error, field func(key string, val interface{}) F) []F {
+	if err == nil {
+		return nil
+	}
+
+	fs := []F{field(KeyError, err.Error())}
+	if cs := errsel.ClassesOf(err); len(cs) > 0 {
+		names := make([]string, len(cs))
+		for i, cls := range cs {
+			names[i] = errsel.Describe(cls)
+		}
+		fs = append(fs, field(KeyClasses, names))
+	}
+
+	causes := errsel.CausesOf(err)
+	fs = append(fs, field(KeyCause, causes[len(causes)-1].Error()))
+
+	if st := errsel.StackOf(err); len(st) > 0 {
+		frames := make([]string, len(st))
+		for i, f := range st {
+			frames[i] = f.String()
+		}
+		fs = append(fs, field(KeyStack, frames))
+	}
+	return fs
+}
+
+// Logger is the part of *zap.Logger used by this package.
+type Logger[F any] interface {
+	Error(msg string, fields ...F)
+}
+
+// ZapOn returns a selector that logs an error to logger at the error level
+// every time sel matches it, and otherwise behaves exactly like sel. The
+// whole error is logged with Fields, under the description of sel.
+//
+//    var unexpected = errselzap.ZapOn(errsel.Not(notFound), logger, zap.Any)
+func ZapOn[F any, L Logger[F]](sel errsel.Selector, logger L, field func(key string, val interface{}) F) errsel.Selector {
+	msg := errsel.Describe(sel)
+	return zapSelector{sel: sel, SelectorFunc: func(err error) (bool, error) {
+		ok, er := sel.Traverse(err)
+		if ok {
+			logger.Error(msg, Fields(err, field)...)
+		}
+		return ok, er
+	}}
+}
+
+// zapSelector is a selector returned by ZapOn, described as zap(sel).
+type zapSelector struct {
+	errsel.SelectorFunc
+	sel errsel.Selector
+}
+
+func (s zapSelector) String() string {
+	return "zap(" + errsel.Describe(s.sel) + ")"
+}
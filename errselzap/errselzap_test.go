@@ -0,0 +1,60 @@
+package errselzap
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/nytopop/errsel"
+	"github.com/stretchr/testify/assert"
+)
+
+type field struct {
+	key string
+	val interface{}
+}
+
+func anyField(key string, val interface{}) field {
+	return field{key, val}
+}
+
+type logger struct {
+	msgs   []string
+	fields [][]field
+}
+
+func (l *logger) Error(msg string, fields ...field) {
+	l.msgs = append(l.msgs, msg)
+	l.fields = append(l.fields, fields)
+}
+
+func TestFields(t *testing.T) {
+	assert.Nil(t, Fields(nil, anyField))
+	assert.Equal(t, []field{{KeyError, "x"}, {KeyCause, "x"}}, Fields(errors.New("x"), anyField))
+
+	a, b := errsel.Named("a"), errsel.Named("b")
+	fs := Fields(a.Lift(fmt.Errorf("y: %w", b.New("x"))), anyField)
+	assert.Len(t, fs, 4)
+	assert.Equal(t, field{KeyError, "a{ y: b{ x } }"}, fs[0])
+	assert.Equal(t, field{KeyClasses, []string{"a", "b"}}, fs[1])
+	assert.Equal(t, field{KeyCause, "x"}, fs[2])
+	assert.Equal(t, KeyStack, fs[3].key)
+	assert.Contains(t, fs[3].val.([]string)[2], "TestFields")
+}
+
+func TestZapOn(t *testing.T) {
+	l := new(logger)
+	a := errsel.Named("a")
+	sel := ZapOn(a, l, anyField)
+
+	assert.True(t, sel.In(a.New("x")))
+	assert.False(t, sel.In(errors.New("x")))
+	assert.Equal(t, []string{"a"}, l.msgs)
+	assert.Equal(t, field{KeyError, "a{ x }"}, l.fields[0][0])
+	assert.Equal(t, "zap(a)", errsel.Describe(sel))
+
+	inner := a.New("x")
+	matched, ok := sel.(errsel.Querier).Query(fmt.Errorf("outer: %w", inner))
+	assert.True(t, ok)
+	assert.Equal(t, inner, matched)
+}
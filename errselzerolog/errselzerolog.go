@@ -0,0 +1,83 @@
+// Package errselzerolog adds classed errors to zerolog events, so that they
+// are logged with a consistent shape: the error's message, its classes, its
+// root cause and the stack trace nearest to where it originated.
+//
+// It doesn't depend on zerolog; any event type with zerolog's Str, Strs and
+// Msg methods will do, which *zerolog.Event satisfies:
+//
+//    errselzerolog.Annotate(logger.Error(), err).Msg("request failed")
+package errselzerolog
+
+import "github.com/nytopop/errsel"
+
+// Field keys used by this package.
+const (
+	KeyError   = "error"
+	KeyClasses = "error_classes"
+	KeyCause   = "error_cause"
+	KeyStack   = "error_stack"
+)
+
+// Event is the part of *zerolog.Event used by this package.
+type Event[E any] interface {
+	Str(key, val string) E
+	Strs(key string, vals []string) E
+	Msg(msg string)
+}
+
+// Annotate adds fields describing err to event, and returns it. The
+// classes and stack are omitted if err has none.
+func Annotate[E Event[E]](event E, err error) E {
+	if err == nil {
+		return event
+	}
+
+	event = event.Str(KeyError, err.Error())
+	if cs := errsel.ClassesOf(err); len(cs) > 0 {
+		names := make([]string, len(cs))
+		for i, cls := range cs {
+			names[i] = errsel.Describe(cls)
+		}
+		event = event.Strs(KeyClasses, names)
+	}
+
+	causes := errsel.CausesOf(err)
+	event = event.Str(KeyCause, causes[len(causes)-1].Error())
+
+	if st := errsel.StackOf(err); len(st) > 0 {
+		frames := make([]string, len(st))
+		for i, f := range st {
+			frames[i] = f.String()
+		}
+		event = event.Strs(KeyStack, frames)
+	}
+	return event
+}
+
+// ZerologOn returns a selector that logs an error with an event from event
+// every time sel matches it, and otherwise behaves exactly like sel. The
+// whole error is added to the event with Annotate, and the event is sent
+// with the description of sel as its message.
+//
+//    var unexpected = errselzerolog.ZerologOn(errsel.Not(notFound), logger.Error)
+func ZerologOn[E Event[E]](sel errsel.Selector, event func() E) errsel.Selector {
+	msg := errsel.Describe(sel)
+	return zerologSelector{sel: sel, SelectorFunc: func(err error) (bool, error) {
+		ok, er := sel.Traverse(err)
+		if ok {
+			Annotate(event(), err).Msg(msg)
+		}
+		return ok, er
+	}}
+}
+
+// zerologSelector is a selector returned by ZerologOn, described as
+// zerolog(sel).
+type zerologSelector struct {
+	errsel.SelectorFunc
+	sel errsel.Selector
+}
+
+func (s zerologSelector) String() string {
+	return "zerolog(" + errsel.Describe(s.sel) + ")"
+}
@@ -0,0 +1,69 @@
+package errselzerolog
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/nytopop/errsel"
+	"github.com/stretchr/testify/assert"
+)
+
+type event struct {
+	out  *[]string
+	strs map[string]interface{}
+}
+
+func (e *event) Str(key, val string) *event {
+	e.strs[key] = val
+	return e
+}
+
+func (e *event) Strs(key string, vals []string) *event {
+	e.strs[key] = vals
+	return e
+}
+
+func (e *event) Msg(msg string) {
+	*e.out = append(*e.out, msg)
+}
+
+func TestAnnotate(t *testing.T) {
+	var out []string
+	a, b := errsel.Named("a"), errsel.Named("b")
+
+	e := Annotate(&event{&out, map[string]interface{}{}}, a.Lift(b.New("x")))
+	assert.Equal(t, "a{ b{ x } }", e.strs[KeyError])
+	assert.Equal(t, []string{"a", "b"}, e.strs[KeyClasses])
+	assert.Equal(t, "x", e.strs[KeyCause])
+	assert.NotEmpty(t, e.strs[KeyStack])
+
+	e = Annotate(&event{&out, map[string]interface{}{}}, errors.New("x"))
+	assert.Equal(t, map[string]interface{}{KeyError: "x", KeyCause: "x"}, e.strs)
+}
+
+func TestZerologOn(t *testing.T) {
+	var (
+		out    []string
+		events []*event
+	)
+	newEvent := func() *event {
+		e := &event{&out, map[string]interface{}{}}
+		events = append(events, e)
+		return e
+	}
+
+	a := errsel.Named("a")
+	sel := ZerologOn(errsel.Or(a), newEvent)
+	assert.True(t, sel.In(a.New("x")))
+	assert.False(t, sel.In(errors.New("x")))
+	assert.Equal(t, []string{"or(a)"}, out)
+	assert.Len(t, events, 1)
+	assert.Equal(t, "a{ x }", events[0].strs[KeyError])
+	assert.Equal(t, "zerolog(or(a))", errsel.Describe(sel))
+
+	inner := a.New("x")
+	matched, ok := ZerologOn(a, newEvent).(errsel.Querier).Query(fmt.Errorf("outer: %w", inner))
+	assert.True(t, ok)
+	assert.Equal(t, inner, matched)
+}
@@ -0,0 +1,166 @@
+package errsel
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Explanation reports how a selector arrived at its result for an error.
+type Explanation struct {
+	// Selector is the selector that was explained.
+	Selector Selector
+
+	// Op names the composition of Selector, such as "and", "or", or "not".
+	// It is empty if Selector is not a composition of other selectors.
+	Op string
+
+	// Matched reports whether Selector matched.
+	Matched bool
+
+	// Skipped reports whether Selector was not evaluated, because a lazy
+	// composition had already decided its result.
+	Skipped bool
+
+	// Error is the intermediate error that Selector matched, if any.
+	Error error
+
+	// Depth is the position of Error in the context chain, in the order
+	// that Causes would visit it, or -1 if Error isn't part of the chain.
+	Depth int
+
+	// Children explains each selector that Selector is composed from.
+	Children []Explanation
+}
+
+// Explain evaluates sel against err, and reports which of the selectors it
+// is composed from matched, at what depth, and how their results combined.
+// It is intended for debugging deeply nested selector trees.
+//
+// Every component selector is evaluated individually, so any side effects
+// they carry (such as those of Call) may fire more than once.
+func Explain(sel Selector, err error) Explanation {
+	x := Explanation{Selector: sel, Depth: -1}
+
+	switch s := sel.(type) {
+	case *Branches:
+		sel = s.node
+	case *ctxNode:
+		sel = s.node
+	}
+
+	n, ok := sel.(*node)
+	if !ok || n.op == "" {
+		x.Matched, x.Error = sel.Traverse(err)
+		x.Depth = depthOf(err, x.Error)
+		return x
+	}
+
+	x.Op = n.op
+	x.Children = make([]Explanation, len(n.args))
+	var decided bool
+	for i, s := range n.args {
+		if decided {
+			x.Children[i] = Explanation{Selector: s, Skipped: true, Depth: -1}
+			continue
+		}
+		x.Children[i] = Explain(s, err)
+
+		switch n.op {
+		case "andl", "andsc":
+			decided = !x.Children[i].Matched
+		case "orsc", "first", "cases":
+			decided = x.Children[i].Matched
+		}
+	}
+
+	switch n.op {
+	case "and", "andl", "andsc", "andc":
+		x.Matched = true
+		for _, c := range x.Children {
+			x.Matched = x.Matched && c.Matched
+		}
+
+	case "or", "orsc", "orc":
+		for _, c := range x.Children {
+			x.Matched = x.Matched || c.Matched
+		}
+
+	case "first", "cases":
+		for _, c := range x.Children {
+			if c.Matched {
+				x.Matched, x.Error, x.Depth = true, c.Error, c.Depth
+				return x
+			}
+		}
+		return x
+
+	case "xor":
+		x.Matched = x.Children[0].Matched != x.Children[1].Matched
+
+	case "not":
+		x.Matched = !x.Children[0].Matched
+
+	default:
+		x.Matched, x.Error = n.Traverse(err)
+		x.Depth = depthOf(err, x.Error)
+		return x
+	}
+
+	if x.Matched {
+		x.Error, x.Depth = err, 0
+	}
+	return x
+}
+
+// depthOf returns the position of target in err's context chain, or -1.
+func depthOf(err, target error) int {
+	depth := -1
+	if target == nil {
+		return depth
+	}
+
+	var i int
+	EachCause(err, func(e error) bool {
+		if sameErr(e, target) {
+			depth = i
+			return false
+		}
+		i++
+		return true
+	})
+	return depth
+}
+
+// String renders the explanation as an indented tree, one selector per
+// line.
+func (x Explanation) String() string {
+	var b strings.Builder
+	x.write(&b, 0)
+	return b.String()
+}
+
+func (x Explanation) write(b *strings.Builder, indent int) {
+	b.WriteString(strings.Repeat("  ", indent))
+
+	name := x.Op
+	if name == "" {
+		name = Describe(x.Selector)
+	}
+	b.WriteString(name)
+
+	switch {
+	case x.Skipped:
+		b.WriteString(": skipped")
+	case x.Matched && x.Depth >= 0:
+		fmt.Fprintf(b, ": matched at depth %d: %v", x.Depth, x.Error)
+	case x.Matched:
+		b.WriteString(": matched")
+	default:
+		b.WriteString(": no match")
+	}
+	b.WriteByte('\n')
+
+	for _, c := range x.Children {
+		c.write(b, indent+1)
+	}
+}
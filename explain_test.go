@@ -0,0 +1,63 @@
+package errsel
+
+import (
+	"os"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExplain(t *testing.T) {
+	database, conflict := Named("database"), Named("conflict")
+	err := errors.Wrap(database.Lift(errors.New("btree")), "query")
+
+	sel := Or(And(database, Not(conflict)), AndL(conflict, Grep("btree")))
+	x := Explain(sel, err)
+
+	assert.True(t, x.Matched)
+	assert.Equal(t, sel.In(err), x.Matched)
+	assert.Equal(t, "or", x.Op)
+	assert.Len(t, x.Children, 2)
+
+	and := x.Children[0]
+	assert.True(t, and.Matched)
+	assert.Equal(t, 2, and.Children[0].Depth)
+	assert.Equal(t, "not", and.Children[1].Op)
+	assert.True(t, and.Children[1].Matched)
+	assert.False(t, and.Children[1].Children[0].Matched)
+
+	andl := x.Children[1]
+	assert.False(t, andl.Matched)
+	assert.True(t, andl.Children[1].Skipped)
+
+	assert.Equal(t, `or: matched at depth 0: query: database{ btree }
+  and: matched at depth 0: query: database{ btree }
+    database: matched at depth 2: database{ btree }
+    not: matched at depth 0: query: database{ btree }
+      conflict: no match
+  andl: no match
+    conflict: no match
+    grep("btree"): skipped
+`, x.String())
+}
+
+func TestDescribe(t *testing.T) {
+	database, conflict := Named("database"), NamedShadow("conflict")
+
+	cases := []struct {
+		sel  Selector
+		desc string
+	}{
+		{And(database, Not(conflict)), "and(database, not(conflict#))"},
+		{Or(Anonymous(), Grep("oops")), `or(anonymous, grep("oops"))`},
+		{AndL(Error(errors.New("eof")), Type(&os.PathError{})), `andl(error("eof"), type(*fs.PathError))`},
+		{OrC(Is(nil), As[*os.LinkError]()), "orc(is(nil), as(*os.LinkError))"},
+		{Bind(database, conflict), "and(database, conflict#)"},
+		{Mask(database), "selector"},
+	}
+
+	for _, c := range cases {
+		assert.Equal(t, c.desc, Describe(c.sel))
+	}
+}
@@ -0,0 +1,53 @@
+package errsel
+
+import (
+	"expvar"
+	"sync"
+	"sync/atomic"
+)
+
+// Names of the expvar maps published by PublishExpvar.
+const (
+	ExpvarLifts   = "errsel.lifts"
+	ExpvarMatches = "errsel.matches"
+)
+
+var (
+	expvarOnce    sync.Once
+	expvarOn      atomic.Bool
+	expvarLifts   *expvar.Map
+	expvarMatches *expvar.Map
+)
+
+// PublishExpvar publishes live error counters with the expvar package, so
+// that they can be inspected at /debug/vars without any metrics stack. Once
+// called, every error lifted into a class is counted under ExpvarLifts by
+// the description of the class, and every match of a selector wrapped with
+// CountMatches is counted under ExpvarMatches by the description of the
+// selector.
+//
+//    func main() {
+//        errsel.PublishExpvar()
+//        http.ListenAndServe(":8080", nil)
+//    }
+//
+// Counting is disabled until PublishExpvar is first called. It is safe to
+// call more than once.
+func PublishExpvar() {
+	expvarOnce.Do(func() {
+		expvarLifts = expvar.NewMap(ExpvarLifts)
+		expvarMatches = expvar.NewMap(ExpvarMatches)
+	})
+	expvarOn.Store(true)
+}
+
+// CountMatches returns a selector that behaves exactly like s, but counts
+// its matches under ExpvarMatches once PublishExpvar has been called.
+func CountMatches(s Selector) Selector {
+	desc := Describe(s)
+	return Call(func(error) {
+		if expvarOn.Load() {
+			expvarMatches.Add(desc, 1)
+		}
+	}, s)
+}
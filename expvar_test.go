@@ -0,0 +1,53 @@
+package errsel
+
+import (
+	"expvar"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// expvarCount returns the counter published under key in the map name, or
+// zero if there is none yet.
+func expvarCount(name, key string) int {
+	m, ok := expvar.Get(name).(*expvar.Map)
+	if !ok || m.Get(key) == nil {
+		return 0
+	}
+	n, _ := strconv.Atoi(m.Get(key).String())
+	return n
+}
+
+func TestPublishExpvar(t *testing.T) {
+	a, b := Named("expvar.a"), NamedShadow("expvar.b")
+	sel := CountMatches(Or(a, b))
+	desc := "or(expvar.a, expvar.b#)"
+
+	// nothing is counted until published; earlier runs may have published
+	if !expvarOn.Load() {
+		lifts := expvarCount(ExpvarLifts, "expvar.a")
+		assert.True(t, sel.In(a.New("x")))
+		assert.Equal(t, lifts, expvarCount(ExpvarLifts, "expvar.a"))
+	}
+
+	PublishExpvar()
+	PublishExpvar()
+	defer expvarOn.Store(false)
+
+	before := map[string]int{
+		"expvar.a":  expvarCount(ExpvarLifts, "expvar.a"),
+		"expvar.b#": expvarCount(ExpvarLifts, "expvar.b#"),
+		"expvar.c":  expvarCount(ExpvarLifts, "expvar.c"),
+		desc:        expvarCount(ExpvarMatches, desc),
+	}
+
+	assert.True(t, sel.In(a.New("x")))
+	assert.True(t, sel.In(Bind(a, Named("expvar.c")).New("x")))
+	_ = b.New("x")
+
+	assert.Equal(t, 2, expvarCount(ExpvarLifts, "expvar.a")-before["expvar.a"])
+	assert.Equal(t, 1, expvarCount(ExpvarLifts, "expvar.b#")-before["expvar.b#"])
+	assert.Equal(t, 1, expvarCount(ExpvarLifts, "expvar.c")-before["expvar.c"])
+	assert.Equal(t, 2, expvarCount(ExpvarMatches, desc)-before[desc])
+}
@@ -0,0 +1,68 @@
+package errsel
+
+// Fields are structured key/value pairs carried alongside an error, for
+// consumption by structured loggers.
+type Fields map[string]interface{}
+
+// fieldsErr annotates an error with structured fields. It is transparent in
+// the error's message.
+type fieldsErr struct {
+	err    error
+	fields Fields
+}
+
+func withFields(err error, fields Fields) error {
+	if err == nil {
+		return nil
+	}
+	cp := make(Fields, len(fields))
+	for k, v := range fields {
+		cp[k] = v
+	}
+	return &fieldsErr{
+		err:    err,
+		fields: cp,
+	}
+}
+
+func (f *fieldsErr) Error() string {
+	return f.err.Error()
+}
+
+func (f *fieldsErr) Cause() error {
+	return f.err
+}
+
+func (f *fieldsErr) Unwrap() error {
+	return f.err
+}
+
+// FieldsOf returns the fields attached anywhere in err's context chain,
+// merged into a single set. If a key is attached more than once, the value
+// nearest to the outside of the chain wins. Like ValueOf, fields attached
+// beneath a shadowing class are not visible.
+//
+//    err = class.WithFields(err, errsel.Fields{"user": id})
+//    logger.Error("request failed", "fields", errsel.FieldsOf(err))
+//
+// FieldsOf returns nil if no fields are attached. Any provided traverse
+// options will scope to causes.
+func FieldsOf(err error, opts ...TraverseOption) Fields {
+	var fields Fields
+	Walk(err, func(e error, info FrameInfo) bool {
+		f, ok := e.(*fieldsErr)
+		if !ok || info.Shadowed {
+			return true
+		}
+		if fields == nil {
+			fields = make(Fields, len(f.fields))
+		}
+		for k, v := range f.fields {
+			if _, ok := fields[k]; !ok {
+				fields[k] = v
+			}
+		}
+		return true
+	}, opts...)
+	return fields
+}
@@ -0,0 +1,23 @@
+package errsel
+
+import (
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFieldsOf(t *testing.T) {
+	cls, shadow := Named("request"), AnonymousShadow()
+
+	hidden := cls.WithFields(errors.New("timeout"), Fields{"secret": "x"})
+	err := cls.WithFields(errors.Wrap(shadow.Lift(hidden), "handler"), Fields{"user": "alice", "attempt": 1})
+	err = cls.WithFields(err, Fields{"attempt": 2})
+
+	assert.Equal(t, Fields{"user": "alice", "attempt": 2}, FieldsOf(err))
+	assert.Equal(t, Fields{"secret": "x"}, FieldsOf(hidden))
+	assert.Equal(t, Fields{"user": "alice", "attempt": 1}, FieldsOf(err, Lens(2)))
+
+	assert.Nil(t, FieldsOf(errors.New("plain")))
+	assert.Nil(t, cls.WithFields(nil, Fields{"user": "alice"}))
+}
@@ -0,0 +1,102 @@
+package errsel
+
+import (
+	"bytes"
+	"encoding/gob"
+	"fmt"
+	"sync/atomic"
+
+	"github.com/pkg/errors"
+)
+
+func init() {
+	gob.RegisterName("errsel.classErr", &classErr{})
+	gob.RegisterName("errsel.fieldsErr", &fieldsErr{})
+}
+
+// GobEncode encodes the context chain of c as a ChainSnapshot, so that
+// classed errors survive net/rpc and gob-based job queues. Classed errors,
+// and errors with fields attached, are registered with encoding/gob, so
+// they can be sent as values of type error. Field values are encoded as
+// strings, as formatted by fmt.Sprint.
+func (c *classErr) GobEncode() ([]byte, error) {
+	return gobEncode(c)
+}
+
+// GobDecode decodes a classed error encoded by GobEncode, restoring it with
+// the registry set by SetGobRegistry; see Registry.Restore. An error lifted
+// into an anonymous class without an identity is decoded into a new
+// anonymous class, which matches nothing else.
+func (c *classErr) GobDecode(data []byte) error {
+	err, gerr := gobDecode(data)
+	if gerr != nil {
+		return gerr
+	}
+
+	restored, ok := err.(*classErr)
+	if !ok {
+		restored = Anonymous().Lift(err).(*classErr)
+	}
+	c.cls, c.also, c.err = restored.cls, restored.also, restored.err
+	c.shadow, c.inst = restored.shadow, restored.inst
+	return nil
+}
+
+// GobEncode encodes the context chain of f; see classErr.GobEncode.
+func (f *fieldsErr) GobEncode() ([]byte, error) {
+	return gobEncode(f)
+}
+
+// GobDecode decodes an error encoded by GobEncode; see classErr.GobDecode.
+func (f *fieldsErr) GobDecode(data []byte) error {
+	err, gerr := gobDecode(data)
+	if gerr != nil {
+		return gerr
+	}
+
+	if restored, ok := err.(*fieldsErr); ok {
+		f.err, f.fields = restored.err, restored.fields
+	} else {
+		f.err, f.fields = err, Fields{}
+	}
+	return nil
+}
+
+var gobRegistry atomic.Pointer[Registry]
+
+// SetGobRegistry sets the registry that classes are resolved with when
+// decoding errors with encoding/gob. Providing nil restores DefaultRegistry,
+// which is used by default. It is safe for concurrent use.
+func SetGobRegistry(r *Registry) {
+	gobRegistry.Store(r)
+}
+
+func gobEncode(err error) ([]byte, error) {
+	snap := Snapshot(err)
+	for _, f := range snap.Frames {
+		for k, v := range f.Fields {
+			f.Fields[k] = fmt.Sprint(v)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(snap); err != nil {
+		return nil, errors.Wrap(err, "errsel: gob encode")
+	}
+	return buf.Bytes(), nil
+}
+
+func gobDecode(data []byte) (error, error) {
+	var snap ChainSnapshot
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&snap); err != nil {
+		return nil, errors.Wrap(err, "errsel: gob decode")
+	}
+	if len(snap.Frames) == 0 {
+		return nil, errors.New("errsel: gob decode: empty snapshot")
+	}
+	r := gobRegistry.Load()
+	if r == nil {
+		r = DefaultRegistry
+	}
+	return r.Restore(snap), nil
+}
@@ -0,0 +1,88 @@
+package errsel
+
+import (
+	"bytes"
+	"encoding/gob"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+type gobJob struct {
+	ID  int
+	Err error
+}
+
+func gobRoundTrip(t *testing.T, err error) error {
+	var buf bytes.Buffer
+	assert.NoError(t, gob.NewEncoder(&buf).Encode(gobJob{ID: 1, Err: err}))
+
+	var job gobJob
+	assert.NoError(t, gob.NewDecoder(&buf).Decode(&job))
+	assert.Equal(t, 1, job.ID)
+	return job.Err
+}
+
+func TestGob(t *testing.T) {
+	r := NewRegistry()
+	SetGobRegistry(r)
+	t.Cleanup(func() { SetGobRegistry(nil) })
+
+	timeout := r.Named("gob.timeout", Code(504))
+	internal, database := NamedShadow("gob.internal"), Named("gob.database")
+
+	orig := timeout.Lift(errors.WithMessage(internal.Lift(database.New("btree")), "query"))
+	err := gobRoundTrip(t, orig)
+
+	assert.Equal(t, orig.Error(), err.Error())
+	assert.True(t, timeout.In(err))
+	assert.True(t, internal.In(err))
+	assert.False(t, database.In(err))
+	assert.True(t, Classes(database.In, IgnoreShadow()).In(err))
+
+	code, ok := CodeOf(err)
+	assert.True(t, ok)
+	assert.Equal(t, 504, code)
+
+	bound := gobRoundTrip(t, Bind(Named("gob.a"), Named("gob.b")).New("x"))
+	assert.True(t, AllOf(Named("gob.a"), Named("gob.b")).In(bound))
+
+	anon := Anonymous()
+	lost := gobRoundTrip(t, anon.New("x"))
+	assert.Equal(t, "x", lost.Error())
+	assert.False(t, anon.In(lost))
+
+	id := NewIdentity()
+	ident, plain := Anonymous(Identity(id)), Named("gob.plain")
+	kept := gobRoundTrip(t, Bind(plain, Anonymous(Identity(id))).New("x"))
+	assert.True(t, AllOf(ident, plain).In(kept))
+	assert.True(t, AnyOf(ident).In(kept))
+
+	r.MustRegister("gob.identity", Anonymous(Identity(id), Code(418)))
+	code, ok = CodeOf(gobRoundTrip(t, ident.New("x")))
+	assert.True(t, ok)
+	assert.Equal(t, 418, code)
+
+	assert.Error(t, new(classErr).GobDecode([]byte("junk")))
+}
+
+func TestGobUninterned(t *testing.T) {
+	name, id := "gob.uninterned."+NewIdentity(), NewIdentity()
+	err := gobRoundTrip(t, (&class{named: true, name: name, id: id, loose: true}).toClass().New("x"))
+
+	assert.Zero(t, lookup(name))
+	assert.Zero(t, lookup(id))
+	assert.True(t, Named(name).In(err))
+	assert.True(t, AnyOf(Anonymous(Identity(id))).In(err))
+	assert.False(t, AnyOf(Named(name, Identity(NewIdentity()))).In(err))
+}
+
+func TestGobFields(t *testing.T) {
+	type user struct{ ID int }
+	cls := Named("gob.fields")
+
+	err := gobRoundTrip(t, cls.WithFields(errors.New("x"), Fields{"user": user{7}, "n": 2}))
+	assert.True(t, cls.In(err))
+	assert.Equal(t, Fields{"user": "{7}", "n": "2"}, FieldsOf(err))
+}
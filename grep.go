@@ -0,0 +1,114 @@
+package errsel
+
+import (
+	stderrors "errors"
+	"io"
+	"reflect"
+
+	"github.com/pkg/errors"
+)
+
+// grep searches for a string in the message of an error without building
+// the message. Layers whose messages are known to be composed of the
+// messages beneath them are streamed through a matcher piece by piece;
+// anything else is searched as an opaque whole.
+type grep struct {
+	pat  string
+	fail []int // KMP failure function of pat
+}
+
+var (
+	withStackType = reflect.TypeOf(errors.WithStack(io.EOF))
+	joinType      = reflect.TypeOf(stderrors.Join(io.EOF))
+)
+
+func newGrep(pat string) *grep {
+	fail := make([]int, len(pat))
+	for i, k := 1, 0; i < len(pat); i++ {
+		for k > 0 && pat[i] != pat[k] {
+			k = fail[k-1]
+		}
+		if pat[i] == pat[k] {
+			k++
+		}
+		fail[i] = k
+	}
+	return &grep{pat: pat, fail: fail}
+}
+
+func (g *grep) match(err error) bool {
+	if err == nil {
+		return false
+	}
+	if g.pat == "" {
+		return true
+	}
+	state := 0
+	return g.err(err, &state, 0)
+}
+
+// err feeds the message of err to the matcher, reporting whether the
+// pattern has been seen.
+func (g *grep) err(err error, state *int, depth int) bool {
+	if max := maxChainLength.Load(); max != 0 && uint64(depth) >= max {
+		return g.feed(err.Error(), state)
+	}
+
+	switch e := err.(type) {
+	case *classErr:
+		if e.cls.soft || e.cls.sensitive() || len(e.also) > 0 {
+			return g.feed(e.Error(), state)
+		}
+		if !decorations.Load() || !e.cls.named {
+			return g.err(e.err, state, depth+1)
+		}
+		if g.feed(e.cls.name, state) {
+			return true
+		}
+		if e.shadow && g.feed("#", state) {
+			return true
+		}
+		return g.feed("{ ", state) || g.err(e.err, state, depth+1) || g.feed(" }", state)
+
+	case *fieldsErr:
+		return g.err(e.err, state, depth+1)
+	case *templateErr:
+		return g.err(e.err, state, depth+1)
+	case *valueErr:
+		return g.err(e.err, state, depth+1)
+	}
+
+	switch reflect.TypeOf(err) {
+	case withStackType:
+		return g.err(err.(causer).Cause(), state, depth+1)
+	case joinType:
+		for i, e := range err.(interface{ Unwrap() []error }).Unwrap() {
+			if i > 0 && g.feed("\n", state) {
+				return true
+			}
+			if g.err(e, state, depth+1) {
+				return true
+			}
+		}
+		return false
+	}
+	return g.feed(err.Error(), state)
+}
+
+// feed advances the matcher over s.
+func (g *grep) feed(s string, state *int) bool {
+	k := *state
+	for i := 0; i < len(s); i++ {
+		for k > 0 && s[i] != g.pat[k] {
+			k = g.fail[k-1]
+		}
+		if s[i] == g.pat[k] {
+			k++
+		}
+		if k == len(g.pat) {
+			return true
+		}
+	}
+	*state = k
+	return false
+}
@@ -0,0 +1,55 @@
+package errsel
+
+import (
+	stderrors "errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGrep(t *testing.T) {
+	db, api, secret := Named("db"), NamedShadow("api"), Named("secret", Sensitive())
+	soft := Named("soft", SoftShadow())
+
+	errs := []error{
+		errors.New("base"),
+		db.New("conn refused"),
+		api.Lift(errors.WithStack(db.Wrap(errors.New("aaab"), "ctx"))),
+		secret.Lift(db.New("password=hunter2")),
+		soft.Lift(db.New("down")),
+		stderrors.Join(db.New("one"), fmt.Errorf("two: %w", Anonymous().New("three"))),
+		db.WithFields(errors.New("f"), Fields{"k": 1}),
+	}
+	pats := []string{"", "base", "db{ conn", "api#{ ctx", "aab", "b }", "hunter2",
+		Redacted, "soft{ down", "db{", "one }\ntwo", "three", "f }", "nope", "{ {"}
+
+	for _, err := range errs {
+		for _, p := range pats {
+			assert.Equal(t, strings.Contains(err.Error(), p), Grep(p).In(err),
+				"grep %q in %q", p, err.Error())
+		}
+	}
+}
+
+func TestGrepAllocs(t *testing.T) {
+	err := Named("a").Lift(errors.WithStack(Named("b").Lift(errors.WithStack(errors.New("deep")))))
+	sel := Grep("b{ deep }")
+	assert.True(t, sel.In(err))
+	assert.Zero(t, testing.AllocsPerRun(100, func() { sel.In(err) }))
+
+	// no limit on chain length still streams
+	SetMaxChainLength(0)
+	defer SetMaxChainLength(DefaultMaxChainLength)
+	assert.True(t, sel.In(err))
+	assert.Zero(t, testing.AllocsPerRun(100, func() { sel.In(err) }))
+}
+
+func BenchmarkGrep(b *testing.B) {
+	sel := Grep("asdfjknaksjdfn")
+	for i := 0; i < b.N; i++ {
+		_ = sel.In(ErrSomeErr)
+	}
+}
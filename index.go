@@ -0,0 +1,68 @@
+package errsel
+
+// classIndex records the class errors that a default traversal of a
+// classErr's context chain would test, in the order it would test them.
+type classIndex struct {
+	errs []*classErr
+	gen  uint64 // of the unwrappers it was built with; see unwrappersGen
+}
+
+var defaultCfg = applyTraverseOpts()
+
+// index returns the class index of c, building it on first use. Context
+// chains are immutable, so the index is computed once per error, unless
+// unwrappers are registered or unregistered in the meantime; concurrent
+// first uses may each build one, but all are equivalent.
+func (c *classErr) index() *classIndex {
+	gen := unwrappersGen.Load()
+	if idx := c.idx.Load(); idx != nil && idx.gen == gen {
+		return idx
+	}
+
+	idx := &classIndex{gen: gen}
+	defaultCfg.walk(c, func(f frame) (bool, bool) {
+		if f.shadowed {
+			return false, false
+		}
+		e, ok := f.err.(*classErr)
+		if !ok {
+			return false, true
+		}
+		idx.errs = append(idx.errs, e)
+		return false, !e.shadow
+	})
+
+	c.idx.Store(idx)
+	return idx
+}
+
+// traverse is the selector of e. Errors that are themselves annotated with
+// a class are answered from their index, so that evaluating many selectors
+// against the same error only walks its context chain once.
+func (e *class) traverse(err error) (bool, error) {
+	ok, er := e.find(err)
+	if ok {
+		countMatch(e)
+	}
+	return ok, er
+}
+
+// match is like traverse, for callers that don't need the matching error.
+func (e *class) match(err error) bool {
+	ok, _ := e.traverse(err)
+	return ok
+}
+
+func (e *class) find(err error) (bool, error) {
+	c, ok := err.(*classErr)
+	if !ok || tracing() {
+		return defaultCfg.walk(err, classes{f: e.in, cfg: defaultCfg}.visit)
+	}
+
+	for _, x := range c.index().errs {
+		if e.in(x) {
+			return true, x
+		}
+	}
+	return false, nil
+}
@@ -0,0 +1,44 @@
+package errsel
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClassIndex(t *testing.T) {
+	a, b, c, shadow := Named("a"), Named("b"), Named("c"), AnonymousShadow()
+	inner := b.New("x")
+	err := a.Lift(fmt.Errorf("wrap: %w", shadow.Lift(inner)))
+
+	idx := err.(*classErr).index()
+	assert.Len(t, idx.errs, 2)
+	assert.True(t, idx == err.(*classErr).index())
+
+	for i := 0; i < 2; i++ {
+		assert.True(t, a.In(err))
+		assert.True(t, shadow.In(err))
+		assert.False(t, b.In(err))
+		assert.False(t, c.In(err))
+		assert.True(t, b.In(inner))
+	}
+
+	ok, at := a.Traverse(err)
+	assert.True(t, ok)
+	assert.Equal(t, err, at)
+
+	// errors that aren't classErrs are traversed as before
+	wrapped := fmt.Errorf("wrap: %w", err)
+	assert.True(t, a.In(wrapped))
+	assert.False(t, b.In(wrapped))
+}
+
+func BenchmarkClassInMany(b *testing.B) {
+	sels := []Class{goodStuff, okayStuff, badStuff, Named("missing")}
+	for i := 0; i < b.N; i++ {
+		for _, s := range sels {
+			_ = s.In(ErrSomeErr)
+		}
+	}
+}
@@ -0,0 +1,57 @@
+package errsel
+
+import "time"
+
+// Instance identifies a specific occurrence of an error, so that it can be
+// correlated between an API response and server logs.
+type Instance struct {
+	// ID uniquely identifies the occurrence.
+	ID string
+
+	// Time is when the error was lifted into its class.
+	Time time.Time
+}
+
+// Instances makes a class record an Instance for every error lifted into
+// it, which can be retrieved with InstanceOf. Classes inherit this from
+// their parents.
+//
+//    var internal = Named("internal", Instances())
+//
+//    err = internal.Lift(err)
+//    inst, _ := errsel.InstanceOf(err)
+//    log.Printf("error %s: %v", inst.ID, err)
+func Instances() ClassOption {
+	return ClassOption(func(e *class) {
+		e.inst = true
+	})
+}
+
+func (e *class) instance() *Instance {
+	for cls := e; cls != nil; cls = cls.parent {
+		if cls.inst {
+			return &Instance{
+				ID:   NewIdentity(),
+				Time: time.Now(),
+			}
+		}
+	}
+	return nil
+}
+
+// InstanceOf returns the instance recorded by the outermost class in err's
+// context chain that records them, respecting class shadowing.
+//
+// Any provided traverse options will scope to classes.
+func InstanceOf(err error, opts ...TraverseOption) (Instance, bool) {
+	var inst *Instance
+	Classes(func(e error) bool {
+		inst = e.(*classErr).inst
+		return inst != nil
+	}, opts...).In(err)
+
+	if inst == nil {
+		return Instance{}, false
+	}
+	return *inst, true
+}
@@ -0,0 +1,31 @@
+package errsel
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInstanceOf(t *testing.T) {
+	internal := Named("internal", Instances())
+	storage := internal.Child("storage")
+
+	before := time.Now()
+	inner := storage.New("disk full")
+	outer := internal.Wrap(inner, "save")
+
+	a, ok := InstanceOf(errors.Wrap(outer, "handler"))
+	assert.True(t, ok)
+	assert.False(t, a.Time.Before(before))
+
+	b, _ := InstanceOf(inner)
+	assert.NotEqual(t, a.ID, b.ID)
+
+	c, _ := InstanceOf(NamedShadow("api").Lift(inner), IgnoreShadow())
+	assert.Equal(t, b, c)
+
+	_, ok = InstanceOf(Named("plain").New("x"))
+	assert.False(t, ok)
+}
@@ -0,0 +1,37 @@
+package errsel
+
+// Interceptor is called with every error lifted into a class, and returns
+// the error to lift in its place. It can be used to rewrite messages, attach
+// values or fields, or count lifts, without losing the class's identity as a
+// selector.
+//
+// If an interceptor returns nil, the error it was called with is kept.
+type Interceptor func(cls Class, err error) error
+
+// Intercept attaches interceptors to a class, which are called in order on
+// every error lifted into it, before it is annotated with the class.
+// Classes inherit the interceptors of their parents, which run after their
+// own.
+//
+//    var lifts atomic.Int64
+//
+//    var api = Named("api", Intercept(func(cls Class, err error) error {
+//        lifts.Add(1)
+//        return err
+//    }))
+func Intercept(fs ...Interceptor) ClassOption {
+	return ClassOption(func(e *class) {
+		e.icept = append(e.icept, fs...)
+	})
+}
+
+func (e *class) intercepted(err error) error {
+	for cls := e; cls != nil; cls = cls.parent {
+		for _, f := range cls.icept {
+			if next := f(e.self, err); next != nil {
+				err = next
+			}
+		}
+	}
+	return err
+}
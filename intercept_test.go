@@ -0,0 +1,42 @@
+package errsel
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIntercept(t *testing.T) {
+	var order []string
+	api := Named("api", Intercept(func(cls Class, err error) error {
+		order = append(order, "api:"+Describe(cls))
+		return withFields(err, Fields{"layer": "api"})
+	}))
+	handler := api.Child("handler", Intercept(
+		func(cls Class, err error) error {
+			order = append(order, "handler")
+			return errors.New(strings.ToUpper(err.Error()))
+		},
+	))
+
+	err := handler.New("oh no")
+	assert.Equal(t, "handler{ OH NO }", err.Error())
+	assert.Equal(t, []string{"handler", "api:handler"}, order)
+	assert.Equal(t, Fields{"layer": "api"}, FieldsOf(err))
+	assert.True(t, api.In(err))
+
+	typed := NamedClassOf[int]("typed", Intercept(func(_ Class, err error) error {
+		return errors.WithMessage(err, "typed")
+	}))
+	err = typed.LiftWith(errors.New("x"), 1)
+	assert.Equal(t, "typed{ typed: x }", err.Error())
+	v, _ := typed.Get(err)
+	assert.Equal(t, 1, v)
+
+	dropped := Named("dropped", Intercept(func(Class, error) error { return nil }))
+	err = dropped.New("x")
+	assert.Equal(t, "dropped{ x }", err.Error())
+	assert.True(t, dropped.In(err))
+}
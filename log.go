@@ -0,0 +1,72 @@
+package errsel
+
+import (
+	"context"
+	"log/slog"
+	"sort"
+)
+
+// LogValue renders classed errors as structured attributes for log/slog:
+// the error's message, the classes annotating its context chain, any fields
+// attached to it with WithFields, and its Instance, if it has one.
+//
+//    logger.Error("request failed", "err", err)
+//    // err.message="api{ not found }" err.classes=[api] err.fields.user=42
+func (c *classErr) LogValue() slog.Value {
+	return logValue(c)
+}
+
+// LogValue renders the error as classErr.LogValue does.
+func (f *fieldsErr) LogValue() slog.Value {
+	return logValue(f)
+}
+
+func logValue(err error) slog.Value {
+	attrs := []slog.Attr{slog.String("message", err.Error())}
+
+	var names []string
+	for _, cls := range ClassesOf(err) {
+		names = append(names, Describe(cls))
+	}
+	if len(names) > 0 {
+		attrs = append(attrs, slog.Any("classes", names))
+	}
+
+	if fields := FieldsOf(err); len(fields) > 0 {
+		keys := make([]string, 0, len(fields))
+		for k := range fields {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		fs := make([]slog.Attr, len(keys))
+		for i, k := range keys {
+			fs[i] = slog.Any(k, fields[k])
+		}
+		attrs = append(attrs, slog.Attr{Key: "fields", Value: slog.GroupValue(fs...)})
+	}
+
+	if inst, ok := InstanceOf(err); ok {
+		attrs = append(attrs, slog.String("instance", inst.ID))
+	}
+	return slog.GroupValue(attrs...)
+}
+
+// LogOn returns a selector that logs an error to logger at level every time
+// s matches it, and otherwise behaves exactly like s. It is like a Call
+// with a logging function, but records the whole error as structured
+// attributes, under "err", as LogValue does; the log message is the
+// description of s. If traversed with TraverseContext, the context is passed
+// to the logger.
+//
+//    var unexpected = LogOn(logger, slog.LevelError, Not(Or(notFound, conflict)))
+func LogOn(logger *slog.Logger, level slog.Level, s Selector) ContextSelector {
+	msg := Describe(s)
+	return composeContext("log", func(ctx context.Context, err error) (bool, error) {
+		ok, er := TraverseContext(ctx, s, err)
+		if ok && logger.Enabled(ctx, level) {
+			logger.LogAttrs(ctx, level, msg, slog.Attr{Key: "err", Value: logValue(err)})
+		}
+		return ok, er
+	}, s)
+}
@@ -0,0 +1,56 @@
+package errsel
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLogValue(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{
+		ReplaceAttr: func(_ []string, a slog.Attr) slog.Attr {
+			if a.Key == slog.TimeKey {
+				return slog.Attr{}
+			}
+			return a
+		},
+	}))
+
+	api, db := Named("api"), Named("db", Instances())
+	err := api.WithFields(db.New("down"), Fields{"user": 42, "op": "get"})
+
+	logger.Error("failed", "err", err)
+	inst, _ := InstanceOf(err)
+	assert.Equal(t, `level=ERROR msg=failed err.message="api{ db{ down } }" err.classes="[api db]" `+
+		`err.fields.op=get err.fields.user=42 err.instance=`+inst.ID+"\n", buf.String())
+
+	buf.Reset()
+	logger.Info("plain", "err", api.New("x"))
+	assert.Equal(t, `level=INFO msg=plain err.message="api{ x }" err.classes=[api]`+"\n", buf.String())
+}
+
+func TestLogOn(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelWarn}))
+	a := Named("a")
+
+	sel := LogOn(logger, slog.LevelWarn, a)
+	err := errors.Wrap(a.New("x"), "y")
+	ok, er := sel.Traverse(err)
+	assert.True(t, ok)
+	assert.True(t, a.In(er))
+	assert.Contains(t, buf.String(), `"msg":"a","err":{"message":"y: a{ x }","classes":["a"]}`)
+
+	buf.Reset()
+	assert.False(t, sel.In(errors.New("x")))
+	assert.True(t, LogOn(logger, slog.LevelInfo, a).In(err))
+	ok, _ = TraverseContext(context.Background(), sel, err)
+	assert.True(t, ok)
+	assert.Equal(t, 1, bytes.Count(buf.Bytes(), []byte("\n")))
+	assert.Equal(t, "log(a)", Describe(sel))
+}
@@ -0,0 +1,101 @@
+package errsel
+
+import "reflect"
+
+// MatchAll returns every intermediate error in err's context chain that sel
+// matches, in traversal order, rather than stopping at the first. An
+// intermediate is included if sel matches it as the error it was called
+// with.
+//
+// Every intermediate is considered, including those hidden by a shadowing
+// class; use MatchAllClasses to respect shadowing.
+//
+// Any provided traverse options will scope to causes.
+func MatchAll(sel Selector, err error, opts ...TraverseOption) []error {
+	var all []error
+	Causes(func(e error) bool {
+		if ok, matched := sel.Traverse(e); ok && sameErr(matched, e) {
+			all = append(all, e)
+		}
+		return false
+	}, opts...).In(err)
+	return all
+}
+
+// MatchAllClasses is like MatchAll, except that only intermediate errors
+// which have been annotated with a class are considered, and class
+// shadowing is respected.
+//
+// Any provided traverse options will scope to classes.
+func MatchAllClasses(sel Selector, err error, opts ...TraverseOption) []error {
+	var all []error
+	Classes(func(e error) bool {
+		if ok, matched := sel.Traverse(e); ok && sameErr(matched, e) {
+			all = append(all, e)
+		}
+		return false
+	}, opts...).In(err)
+	return all
+}
+
+// sameErr reports whether a and b are the same error, without panicking on
+// errors of uncomparable types.
+func sameErr(a, b error) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	t := reflect.TypeOf(a)
+	if t != reflect.TypeOf(b) || !t.Comparable() {
+		return false
+	}
+	return a == b
+}
+
+// ClassesOf returns every class that annotates err's context chain, from
+// the outermost inward. Classes hidden beneath a shadowing class are not
+// included, unless IgnoreShadow is provided.
+//
+// Any provided traverse options will scope to classes.
+func ClassesOf(err error, opts ...TraverseOption) []Class {
+	var all []Class
+	Classes(func(e error) bool {
+		for cls := range e.(*classErr).classes {
+			all = append(all, cls.self)
+		}
+		return false
+	}, opts...).In(err)
+	return all
+}
+
+// CausesOf returns every intermediate error in err's context chain, from
+// the outermost inward, in the order that Causes would visit them. Errors
+// beneath a class that scrubs messages are returned wrapped, so that their
+// messages are scrubbed too; see Scrub.
+//
+// For deep chains, EachCause visits the same errors, unwrapped, without
+// allocating the full slice.
+func CausesOf(err error, opts ...TraverseOption) []error {
+	var (
+		all []error
+		s   scrubbing
+	)
+	Walk(err, func(e error, info FrameInfo) bool {
+		if sc := s.visit(e, info.Depth); len(sc) > 0 {
+			e = &scrubErr{err: e, msg: scrubAll(e.Error(), sc), scrubbers: sc}
+		}
+		all = append(all, e)
+		return true
+	}, opts...)
+	return all
+}
+
+// EachCause calls f with every intermediate error in err's context chain,
+// in the order that Causes would visit them, until f returns false.
+func EachCause(err error, f func(error) bool, opts ...TraverseOption) {
+	if err == nil {
+		return
+	}
+	Causes(func(e error) bool {
+		return !f(e)
+	}, opts...).In(err)
+}
@@ -0,0 +1,103 @@
+package errsel
+
+import (
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMatchAll(t *testing.T) {
+	audit, shadow := Named("audit"), NamedShadow("shadow")
+
+	hidden := audit.New("hidden")
+	middle := audit.Lift(shadow.Lift(hidden))
+	err := errors.Wrap(audit.Lift(errors.Wrap(middle, "wrap")), "top")
+
+	all := MatchAll(audit, err)
+	assert.Len(t, all, 3)
+	assert.Equal(t, middle, all[1])
+	assert.Equal(t, hidden, all[2])
+
+	classed := MatchAllClasses(audit, err)
+	assert.Len(t, classed, 2)
+	assert.Equal(t, all[:2], classed)
+
+	assert.Len(t, MatchAll(Grep("hidden"), err), 9)
+	assert.Empty(t, MatchAll(Named("nope"), err))
+}
+
+func TestClassesOf(t *testing.T) {
+	a, b, shadow := Named("a"), Anonymous(), AnonymousShadow()
+	err := Binds(a, b).Lift(errors.Wrap(shadow.Lift(a.New("deep")), "wrap"))
+
+	assert.Equal(t, []Class{a, b, shadow}, ClassesOf(err))
+	assert.Equal(t, []Class{a, b, shadow, a}, ClassesOf(err, IgnoreShadow()))
+	assert.Empty(t, ClassesOf(errors.New("plain")))
+	assert.Empty(t, ClassesOf(nil))
+}
+
+func TestCausesOf(t *testing.T) {
+	root := errors.New("root")
+	mid := errors.WithMessage(root, "mid")
+	err := errors.WithMessage(mid, "top")
+
+	assert.Equal(t, []error{err, mid, root}, CausesOf(err))
+	assert.Equal(t, []error{mid}, CausesOf(err, Lens(1), Depth(1)))
+	assert.Empty(t, CausesOf(nil))
+
+	var visited []error
+	EachCause(err, func(e error) bool {
+		visited = append(visited, e)
+		return e != mid
+	})
+	assert.Equal(t, []error{err, mid}, visited)
+}
+
+func TestWalk(t *testing.T) {
+	a, shadow := Named("a"), NamedShadow("shadow")
+	root := errors.New("root")
+	err := errors.WithMessage(shadow.Lift(a.Lift(root)), "top")
+
+	var infos []FrameInfo
+	Walk(err, func(e error, info FrameInfo) bool {
+		infos = append(infos, info)
+		return true
+	})
+
+	assert.Equal(t, []FrameInfo{
+		{Depth: 0},
+		{Depth: 1, Class: shadow, Shadow: true},
+		{Depth: 2, Class: a, Shadowed: true},
+		{Depth: 3, Shadowed: true},
+	}, infos)
+
+	var n int
+	Walk(err, func(e error, info FrameInfo) bool {
+		n++
+		return info.Class == nil
+	})
+	assert.Equal(t, 2, n)
+}
+
+func TestChain(t *testing.T) {
+	root := errors.New("root")
+	err := errors.WithMessage(errors.WithMessage(root, "mid"), "top")
+
+	var all []error
+	for e := range Chain(err) {
+		all = append(all, e)
+	}
+	assert.Equal(t, CausesOf(err), all)
+
+	for e := range Chain(err, Lens(2)) {
+		assert.Equal(t, root, e)
+	}
+
+	var n int
+	for range Chain(err) {
+		n++
+		break
+	}
+	assert.Equal(t, 1, n)
+}
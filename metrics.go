@@ -0,0 +1,88 @@
+package errsel
+
+// Labels are the dimensions of a measurement.
+type Labels map[string]string
+
+// Metrics receives measurements of errors, so that they can be reported to
+// any metrics system without this package depending on one. Adapters are
+// typically a few lines long; for Prometheus:
+//
+//    type promMetrics struct {
+//        counters  map[string]*prometheus.CounterVec
+//        observers map[string]*prometheus.HistogramVec
+//    }
+//
+//    func (m promMetrics) Inc(name string, labels errsel.Labels) {
+//        if c, ok := m.counters[name]; ok {
+//            c.With(prometheus.Labels(labels)).Inc()
+//        }
+//    }
+//
+//    func (m promMetrics) Observe(name string, value float64, labels errsel.Labels) {
+//        if o, ok := m.observers[name]; ok {
+//            o.With(prometheus.Labels(labels)).Observe(value)
+//        }
+//    }
+//
+// And for statsd, where labels become tags:
+//
+//    type statsdMetrics struct{ c *statsd.Client }
+//
+//    func (m statsdMetrics) Inc(name string, labels errsel.Labels) {
+//        m.c.Incr(name, tags(labels), 1)
+//    }
+//
+//    func (m statsdMetrics) Observe(name string, value float64, labels errsel.Labels) {
+//        m.c.Histogram(name, value, tags(labels), 1)
+//    }
+//
+// Implementations must be safe for concurrent use.
+type Metrics interface {
+	// Inc increments the counter called name.
+	Inc(name string, labels Labels)
+
+	// Observe records a value of the distribution called name.
+	Observe(name string, value float64, labels Labels)
+}
+
+const (
+	// MetricLifts counts errors lifted into classes measured with
+	// Measured, labeled by class.
+	MetricLifts = "errsel_lifts_total"
+
+	// MetricChainDepth observes the depth of the context chains of errors
+	// lifted into classes measured with Measured, labeled by class.
+	MetricChainDepth = "errsel_chain_depth"
+)
+
+// Measured makes a class report every error lifted into it to m: it
+// increments MetricLifts, and observes the depth of the lifted error's
+// context chain, not counting the class annotation, as MetricChainDepth.
+// Both are labeled with the description of the class under "class".
+// Classes inherit this from their parents, but report under their own
+// descriptions.
+//
+//    var conflict = Named("conflict", Measured(metrics))
+func Measured(m Metrics) ClassOption {
+	return Intercept(func(cls Class, err error) error {
+		labels := Labels{"class": Describe(cls)}
+		m.Inc(MetricLifts, labels)
+		m.Observe(MetricChainDepth, float64(defaultCfg.length(err)), labels)
+		return err
+	})
+}
+
+// CountInto returns a function that increments the counter called name in
+// m, for use with Call, Once and the like. Increments are labeled with the
+// description of the outermost class of the error, if any, under "class".
+//
+//    var retryable = Call(CountInto(metrics, "retries_total"), Or(timeout, conflict))
+func CountInto(m Metrics, name string) func(error) {
+	return func(err error) {
+		labels := Labels{}
+		if cs := ClassesOf(err); len(cs) > 0 {
+			labels["class"] = Describe(cs[0])
+		}
+		m.Inc(name, labels)
+	}
+}
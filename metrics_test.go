@@ -0,0 +1,69 @@
+package errsel
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+type testMetrics struct {
+	mu     sync.Mutex
+	counts map[string]int
+	values map[string][]float64
+}
+
+func newTestMetrics() *testMetrics {
+	return &testMetrics{counts: map[string]int{}, values: map[string][]float64{}}
+}
+
+func (m *testMetrics) key(name string, labels Labels) string {
+	return name + "{" + labels["class"] + "}"
+}
+
+func (m *testMetrics) Inc(name string, labels Labels) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.counts[m.key(name, labels)]++
+}
+
+func (m *testMetrics) Observe(name string, value float64, labels Labels) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	k := m.key(name, labels)
+	m.values[k] = append(m.values[k], value)
+}
+
+func TestMeasured(t *testing.T) {
+	m := newTestMetrics()
+	a := Named("a", Measured(m))
+	b := a.Child("b")
+
+	_ = a.New("x")
+	_ = b.Lift(errors.Wrap(errors.New("x"), "y"))
+	assert.Equal(t, 1, m.counts["errsel_lifts_total{a}"])
+	assert.Equal(t, 1, m.counts["errsel_lifts_total{b}"])
+	assert.Equal(t, []float64{1}, m.values["errsel_chain_depth{a}"])
+	assert.Equal(t, []float64{3}, m.values["errsel_chain_depth{b}"])
+}
+
+func TestCountInto(t *testing.T) {
+	m := newTestMetrics()
+	a, b := Named("a"), NamedShadow("b")
+	count := CountInto(m, "matched")
+
+	sel := Call(count, Or(a, Error(errRoot)))
+	once := Once(count, b)
+	for i := 0; i < 2; i++ {
+		assert.True(t, sel.In(a.New("x")))
+		assert.True(t, once.In(b.Lift(a.New("x"))))
+	}
+	assert.True(t, sel.In(errRoot))
+
+	assert.Equal(t, 2, m.counts["matched{a}"])
+	assert.Equal(t, 1, m.counts["matched{b#}"])
+	assert.Equal(t, 1, m.counts["matched{}"])
+}
+
+var errRoot = errors.New("root")
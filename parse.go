@@ -0,0 +1,178 @@
+package errsel
+
+import "strings"
+
+// ParseClassed reconstructs the class annotations of an error from its
+// rendered message, such as a line of a log or a message forwarded by a
+// third party. It reports whether s held any class annotations; if it
+// didn't, or if they aren't well formed, it returns nil and false.
+//
+// Class annotations are rendered by Error with the following grammar, in
+// which text is any run of characters that doesn't form an annotation:
+//
+//    message    = { text | annotation }
+//    annotation = name [ "#" ] "{ " message " }"
+//    name       = namechar { namechar }
+//    namechar   = letter | digit | "." | "_" | "-" | "/"
+//
+// A "#" marks a shadowing class. So the message
+//
+//    update: conflict{ query: internal#{ btree } }
+//
+// is parsed into an error that conflict and internal match, while btree,
+// hidden beneath internal, is visible only with IgnoreShadow. The parsed
+// error renders exactly as s does, unless s holds the unredacted message of
+// a sensitive class, which is redacted again; see Sensitive.
+//
+// Classes are resolved by name with DefaultRegistry, so that they carry the
+// same options as the classes that rendered s; classes that aren't
+// registered are parsed as named classes, which match any class of the same
+// name, without interning their names. Errors aren't lifted into them, so
+// parsing has none of the side effects of lifting, such as default messages
+// or interceptors. Names that don't follow the grammar, and anonymous
+// classes, aren't rendered in a recoverable form, and are parsed as text.
+// Text that itself holds "{ " or " }" is ambiguous, and may not parse as
+// intended.
+func ParseClassed(s string) (error, bool) {
+	return DefaultRegistry.ParseClassed(s)
+}
+
+// ParseClassed is like the package level ParseClassed, but resolves classes
+// by name with r.
+func (r *Registry) ParseClassed(s string) (error, bool) {
+	p := parser{r: r, s: s}
+	err, ok := p.message(true)
+	if !ok || !p.found {
+		return nil, false
+	}
+	return err, true
+}
+
+// parser parses the rendered form of class annotations.
+type parser struct {
+	r     *Registry
+	s     string
+	i     int
+	found bool // whether any annotations were parsed
+}
+
+// message parses a message up to the end of its annotation, or to the end
+// of the string if top is set.
+func (p *parser) message(top bool) (error, bool) {
+	var (
+		parts []error
+		text  strings.Builder
+	)
+	for {
+		rest := p.s[p.i:]
+		open, close := strings.Index(rest, "{ "), strings.Index(rest, " }")
+		if top {
+			close = -1
+		}
+
+		switch {
+		case close >= 0 && (open < 0 || close < open):
+			text.WriteString(rest[:close])
+			p.i += close
+			return joinParsed(parts, text.String()), true
+
+		case open < 0 && top:
+			text.WriteString(rest)
+			p.i = len(p.s)
+			return joinParsed(parts, text.String()), true
+
+		case open < 0:
+			return nil, false // unterminated annotation
+		}
+
+		name, shadow := annotationName(rest[:open])
+		if name == "" {
+			text.WriteString(rest[:open+2])
+			p.i += open + 2
+			continue
+		}
+
+		text.WriteString(rest[:open-len(name)-len(shadow)])
+		if text.Len() > 0 {
+			parts = append(parts, textErr(text.String()))
+			text.Reset()
+		}
+
+		p.i += open + 2
+		inner, ok := p.message(false)
+		if !ok {
+			return nil, false
+		}
+		p.i += 2 // " }"
+		p.found = true
+		cls, _ := p.r.resolve(name, "", shadow != "", nil)
+		parts = append(parts, annotate(cls, nil, shadow != "", inner))
+	}
+}
+
+// annotationName returns the name and shadow mark that end text, if any.
+func annotationName(text string) (name, shadow string) {
+	if strings.HasSuffix(text, "#") {
+		text, shadow = text[:len(text)-1], "#"
+	}
+	i := len(text)
+	for i > 0 && isNameChar(text[i-1]) {
+		i--
+	}
+	if i == len(text) {
+		return "", ""
+	}
+	return text[i:], shadow
+}
+
+func isNameChar(c byte) bool {
+	switch {
+	case 'a' <= c && c <= 'z', 'A' <= c && c <= 'Z', '0' <= c && c <= '9':
+		return true
+	}
+	return strings.IndexByte("._-/", c) >= 0
+}
+
+// joinParsed joins the parts of a parsed message, ending with text.
+func joinParsed(parts []error, text string) error {
+	if text != "" {
+		parts = append(parts, textErr(text))
+	}
+	switch len(parts) {
+	case 0:
+		return textErr("")
+	case 1:
+		return parts[0]
+	default:
+		return parsedErr(parts)
+	}
+}
+
+// textErr is parsed text that isn't a class annotation.
+type textErr string
+
+func (e textErr) Error() string {
+	return string(e)
+}
+
+// parsedErr is a parsed message holding annotations alongside text, or
+// several annotations. Each annotation is a separate branch beneath it.
+type parsedErr []error
+
+func (e parsedErr) Error() string {
+	var b strings.Builder
+	for _, err := range e {
+		b.WriteString(err.Error())
+	}
+	return b.String()
+}
+
+func (e parsedErr) Unwrap() []error {
+	var errs []error
+	for _, err := range e {
+		if _, ok := err.(textErr); !ok {
+			errs = append(errs, err)
+		}
+	}
+	return errs
+}
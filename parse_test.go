@@ -0,0 +1,70 @@
+package errsel
+
+import (
+	stderrors "errors"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseClassed(t *testing.T) {
+	r := NewRegistry()
+	conflict := r.Named("parse.conflict", Code(409))
+	internal, database := NamedShadow("parse.internal"), Named("parse.database")
+
+	orig := stderrors.Join(
+		errors.WithMessage(conflict.Lift(internal.Wrap(database.New("btree"), "query")), "update"),
+		Bind(Named("parse.a"), Named("parse.b")).New("x"),
+	)
+	msg := orig.Error()
+
+	err, ok := r.ParseClassed(msg)
+	assert.True(t, ok)
+	assert.Equal(t, msg, err.Error())
+	assert.True(t, AllOf(conflict, internal, Named("parse.a"), Named("parse.b")).In(err))
+	assert.False(t, database.In(err))
+	assert.True(t, Classes(database.In, IgnoreShadow()).In(err))
+
+	code, ok := CodeOf(err)
+	assert.True(t, ok)
+	assert.Equal(t, 409, code)
+
+	var lifts int
+	stale := r.Named("parse.stale", DefaultMessage("resource version conflict"),
+		Intercept(func(_ Class, err error) error {
+			lifts++
+			return err
+		}))
+	for _, s := range []string{
+		"parse.stale{ resource version conflict: x }",
+		"parse.stale#{ x }",
+	} {
+		err, ok := r.ParseClassed(s)
+		assert.True(t, ok, s)
+		assert.Equal(t, s, err.Error())
+		assert.True(t, stale.In(err), s)
+	}
+	assert.Zero(t, lifts)
+
+	for _, s := range []string{
+		"no classes here",
+		"parse.a{ unterminated",
+		"parse.a{ parse.b{ x }",
+	} {
+		err, ok := ParseClassed(s)
+		assert.False(t, ok, s)
+		assert.Nil(t, err, s)
+	}
+
+	for _, s := range []string{
+		"{ literal } parse.a{  }",
+		"parse.a{ x } trailing",
+		"prefix - parse.a#{ } }",
+	} {
+		err, ok := ParseClassed(s)
+		assert.True(t, ok, s)
+		assert.Equal(t, s, err.Error())
+		assert.True(t, Named("parse.a").In(err), s)
+	}
+}
@@ -0,0 +1,72 @@
+package errsel
+
+// TypedClass is a class whose errors can carry a typed payload, such as a
+// conflicting key or a retry hint, without defining a custom error type.
+type TypedClass[T any] struct {
+	Class
+	cls *class
+}
+
+// ClassOf returns an anonymous class whose errors can carry a payload of
+// type T.
+//
+//    var conflict = ClassOf[string]()
+//
+//    err := conflict.LiftWith(err, "users/42")
+//    if key, ok := conflict.Get(err); ok {
+//        // key == "users/42"
+//    }
+func ClassOf[T any](opts ...ClassOption) *TypedClass[T] {
+	return typed[T](&class{}, opts)
+}
+
+// NamedClassOf returns a named class whose errors can carry a payload of
+// type T.
+func NamedClassOf[T any](name string, opts ...ClassOption) *TypedClass[T] {
+	return typed[T](&class{named: true, name: name}, opts)
+}
+
+func typed[T any](e *class, opts []ClassOption) *TypedClass[T] {
+	return &TypedClass[T]{
+		Class: e.apply(opts),
+		cls:   e,
+	}
+}
+
+// LiftWith lifts err into the class, attaching payload to it. If err is
+// nil, LiftWith returns nil.
+func (c *TypedClass[T]) LiftWith(err error, payload T) error {
+	if err == nil {
+		return nil
+	}
+	return c.cls.liftVal(err, payload)
+}
+
+// Get returns the payload of the outermost error in err's context chain
+// that was lifted into the class with a payload, respecting class
+// shadowing.
+//
+// Any provided traverse options will scope to classes.
+func (c *TypedClass[T]) Get(err error, opts ...TraverseOption) (T, bool) {
+	var (
+		val   T
+		found bool
+	)
+	Classes(func(e error) bool {
+		ce := e.(*classErr)
+		if !c.cls.in(ce) {
+			return false
+		}
+		val, found = ce.val.(T)
+		return found
+	}, opts...).In(err)
+	return val, found
+}
+
+func (c *TypedClass[T]) Query(err error) (error, bool) {
+	return queryOf(c, err)
+}
+
+func (c *TypedClass[T]) native() *class {
+	return c.cls
+}
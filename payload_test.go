@@ -0,0 +1,40 @@
+package errsel
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+type retryHint struct {
+	after time.Duration
+}
+
+func TestTypedClass(t *testing.T) {
+	conflict := NamedClassOf[string]("conflict")
+	retry := ClassOf[retryHint]()
+
+	err := retry.LiftWith(errors.Wrap(conflict.LiftWith(errors.New("stale"), "users/42"), "update"), retryHint{time.Second})
+
+	key, ok := conflict.Get(err)
+	assert.True(t, ok)
+	assert.Equal(t, "users/42", key)
+
+	hint, ok := retry.Get(err)
+	assert.True(t, ok)
+	assert.Equal(t, time.Second, hint.after)
+
+	assert.True(t, conflict.In(err))
+	assert.True(t, Named("conflict").In(err))
+	assert.Equal(t, "update: conflict{ stale }", err.Error())
+
+	_, ok = conflict.Get(conflict.New("no payload"))
+	assert.False(t, ok)
+
+	_, ok = conflict.Get(AnonymousShadow().Lift(err))
+	assert.False(t, ok)
+
+	assert.Nil(t, conflict.LiftWith(nil, "x"))
+}
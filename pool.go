@@ -0,0 +1,51 @@
+package errsel
+
+import "sync"
+
+var classErrPool = sync.Pool{
+	New: func() interface{} { return new(classErr) },
+}
+
+// Pooled makes a class draw the errors it lifts from a pool, rather than
+// allocating them. Errors of pooled classes may be returned to the pool
+// with Release once they are no longer referenced, which reduces garbage
+// collection pressure in services that lift and discard many errors.
+//
+//    var timeout = Named("timeout", Pooled())
+//
+//    err := timeout.Lift(ctx.Err())
+//    if retryable.In(err) {
+//        errsel.Release(err)
+//        continue
+//    }
+//
+// Errors that are never released are simply collected as usual.
+func Pooled() ClassOption {
+	return ClassOption(func(e *class) {
+		e.pool = true
+	})
+}
+
+// Release returns the errors of pooled classes along err's chain of causes
+// to their pool. It stops at aggregates of several errors, and leaves errors
+// of other classes untouched.
+//
+// After Release, neither err nor any error wrapped by it may be used
+// again; doing so will observe some unrelated error, or none at all. It
+// is safe to call Release on nil.
+func Release(err error) {
+	for err != nil {
+		c, ok := err.(*classErr)
+		if !ok {
+			err, _ = defaultCfg.next(err)
+			continue
+		}
+
+		err = c.err
+		if c.cls.pool {
+			c.cls, c.err, c.val, c.shadow, c.inst = nil, nil, nil, false, nil
+			c.idx.Store(nil)
+			classErrPool.Put(c)
+		}
+	}
+}
@@ -0,0 +1,45 @@
+package errsel
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPooled(t *testing.T) {
+	pooled, plain := Named("pooled", Pooled()), Named("plain")
+
+	err := pooled.Lift(fmt.Errorf("ctx: %w", plain.Lift(pooled.New("x"))))
+	assert.Equal(t, "pooled{ ctx: plain{ pooled{ x } } }", err.Error())
+	assert.True(t, plain.In(err))
+
+	Release(err)
+	Release(nil)
+
+	assert.Nil(t, err.(*classErr).cls)
+	assert.Nil(t, err.(*classErr).idx.Load())
+	assert.Equal(t, "plain{ ctx }", fmt.Sprint(plain.Lift(errors.New("ctx"))))
+
+	// recycled errors are reinitialized
+	again := pooled.New("y")
+	assert.Equal(t, "pooled{ y }", again.Error())
+	assert.False(t, plain.In(again))
+}
+
+func BenchmarkLift(b *testing.B) {
+	cls, cause := Named("plain"), errors.New("x")
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		_ = cls.Lift(cause)
+	}
+}
+
+func BenchmarkLiftPooled(b *testing.B) {
+	cls, cause := Named("pooled", Pooled()), errors.New("x")
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		Release(cls.Lift(cause))
+	}
+}
@@ -0,0 +1,26 @@
+package errsel
+
+// publicKey is the value key under which public messages are attached.
+type publicKey struct{}
+
+func withPublic(err error, public string) error {
+	return withValue(err, publicKey{}, public)
+}
+
+// PublicMessageOf returns the public message attached with WrapPublic
+// nearest to the outside of err's context chain. Public messages attached
+// beneath a shadowing class are not visible, so a boundary can shadow
+// internal errors without leaking their public messages either.
+//
+//    if msg, ok := errsel.PublicMessageOf(err); ok {
+//        http.Error(w, msg, http.StatusBadRequest)
+//    }
+//
+// Any provided traverse options will scope to causes.
+func PublicMessageOf(err error, opts ...TraverseOption) (string, bool) {
+	v, ok := ValueOf(err, publicKey{}, opts...)
+	if !ok {
+		return "", false
+	}
+	return v.(string), true
+}
@@ -0,0 +1,30 @@
+package errsel
+
+import (
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPublicMessageOf(t *testing.T) {
+	validation, api := Named("validation"), NamedShadow("api")
+
+	err := validation.WrapPublic(errors.New("column email violates check"), "insert user", "invalid email address")
+	assert.Equal(t, "validation{ insert user: column email violates check }", err.Error())
+
+	msg, ok := PublicMessageOf(errors.Wrap(err, "handler"))
+	assert.True(t, ok)
+	assert.Equal(t, "invalid email address", msg)
+
+	msg, _ = PublicMessageOf(validation.WrapPublic(err, "retry", "try again later"))
+	assert.Equal(t, "try again later", msg)
+
+	_, ok = PublicMessageOf(api.Lift(err))
+	assert.False(t, ok)
+
+	_, ok = PublicMessageOf(errors.New("plain"))
+	assert.False(t, ok)
+
+	assert.Nil(t, validation.WrapPublic(nil, "insert user", "invalid email address"))
+}
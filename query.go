@@ -0,0 +1,388 @@
+package errsel
+
+import (
+	"fmt"
+	"path"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// QueryOption configures the compilation of a query.
+type QueryOption func(*queryEnv)
+
+type queryEnv struct {
+	errs  map[string]error
+	types map[string]reflect.Type
+}
+
+// DefineError makes err available to queries as type[name], matching errors
+// that are err itself.
+//
+//    errsel.Query("//type[sql.ErrNoRows]", errsel.DefineError("sql.ErrNoRows", sql.ErrNoRows))
+func DefineError(name string, err error) QueryOption {
+	return QueryOption(func(env *queryEnv) {
+		env.errs[name] = err
+	})
+}
+
+// DefineType makes the type of t available to queries as type[name],
+// matching errors of the same type, as Type does.
+func DefineType(name string, t interface{}) QueryOption {
+	return QueryOption(func(env *queryEnv) {
+		env.types[name] = reflect.TypeOf(t)
+	})
+}
+
+// Query compiles a path query over context chains into a selector. Queries
+// use a small, XPath-like language, so that conditions on the structure of a
+// chain can be expressed in configuration rather than Go.
+//
+// A query is a sequence of steps, each preceded by "/" to step to the
+// errors directly beneath the current ones, or by "//" to step to any error
+// at or beneath them. The outermost error of a chain is directly beneath the
+// start of the query. Each step is one of:
+//
+//    class    an error annotated with a class
+//    error    any error; "*" is equivalent
+//    type     an error defined with DefineError or DefineType
+//    ..       the error directly above the current one
+//    .        the current error
+//
+// Tests can be filtered by predicates in square brackets:
+//
+//    class[name='x']    classes that match Named("x")
+//    class[tag='x']     classes with tag x; see Tagged
+//    class[prefix='x']  classes within namespace x; see Prefix
+//    class[glob='x.*']  classes with names matching a glob; see NamedGlob
+//    class[shadow]      shadowing classes
+//    type[name]         errors defined under name
+//    *[grep='x']        errors whose message contains x
+//
+// For example, the following matches chains where a database class wraps
+// sql.ErrNoRows, possibly through other errors:
+//
+//    //class[name='database']//type[sql.ErrNoRows]
+//
+// Queries see the raw structure of a chain, so they don't respect class
+// shadowing. A matching query returns the first error selected by its last
+// step, in the order that Causes would visit them.
+func Query(expr string, opts ...QueryOption) (Selector, error) {
+	env := &queryEnv{
+		errs:  make(map[string]error),
+		types: make(map[string]reflect.Type),
+	}
+	for _, f := range opts {
+		f(env)
+	}
+
+	p := &queryParser{expr: expr, env: env}
+	steps, err := p.parse()
+	if err != nil {
+		return nil, err
+	}
+
+	q := &query{steps: steps, cfg: applyTraverseOpts()}
+	return describe("query("+strconv.Quote(expr)+")", SelectorFunc(q.traverse)), nil
+}
+
+// MustQuery is like Query, but panics if the query can't be compiled. It
+// simplifies the initialization of package level selectors.
+func MustQuery(expr string, opts ...QueryOption) Selector {
+	sel, err := Query(expr, opts...)
+	if err != nil {
+		panic(err)
+	}
+	return sel
+}
+
+const (
+	axisChild = iota
+	axisDescendant
+)
+
+type queryStep struct {
+	axis   int
+	parent bool // step to the error above, rather than beneath
+	self   bool
+	test   func(error) bool
+}
+
+type query struct {
+	steps []queryStep
+	cfg   *traverseConfig
+}
+
+// queryNode is an error in the flattened tree of a context chain. Nodes are
+// kept in preorder, so the descendants of a node directly follow it.
+type queryNode struct {
+	err    error
+	parent int
+	depth  int
+}
+
+func (q *query) traverse(err error) (bool, error) {
+	if err == nil {
+		return false, nil
+	}
+
+	// node 0 is the start of the query, directly above the outermost error
+	nodes := []queryNode{{parent: -1, depth: -1}}
+	q.cfg.walk(err, func(f frame) (bool, bool) {
+		parent := len(nodes) - 1
+		for nodes[parent].depth >= int(f.depth) {
+			parent = nodes[parent].parent
+		}
+		nodes = append(nodes, queryNode{err: f.err, parent: parent, depth: int(f.depth)})
+		return false, true
+	})
+
+	set := make([]bool, len(nodes))
+	set[0] = true
+	for _, s := range q.steps {
+		set = s.apply(nodes, set)
+	}
+
+	for i := 1; i < len(nodes); i++ {
+		if set[i] {
+			return true, nodes[i].err
+		}
+	}
+	return false, nil
+}
+
+func (s queryStep) apply(nodes []queryNode, set []bool) []bool {
+	if s.axis == axisDescendant {
+		all := make([]bool, len(nodes))
+		for i := range nodes {
+			if !set[i] {
+				continue
+			}
+			all[i] = true
+			for j := i + 1; j < len(nodes) && nodes[j].depth > nodes[i].depth; j++ {
+				all[j] = true
+			}
+		}
+		set = all
+	}
+
+	out := make([]bool, len(nodes))
+	for i := range nodes {
+		switch {
+		case s.self:
+			out[i] = set[i]
+		case s.parent:
+			if set[i] && i > 0 {
+				out[nodes[i].parent] = true
+			}
+		default:
+			out[i] = i > 0 && set[nodes[i].parent] && s.test(nodes[i].err)
+		}
+	}
+	return out
+}
+
+type queryParser struct {
+	expr string
+	pos  int
+	env  *queryEnv
+}
+
+func (p *queryParser) errorf(format string, args ...interface{}) error {
+	return errors.Errorf("errsel: invalid query %q at offset %d: %s",
+		p.expr, p.pos, fmt.Sprintf(format, args...))
+}
+
+func (p *queryParser) parse() ([]queryStep, error) {
+	var steps []queryStep
+	for p.pos < len(p.expr) {
+		var s queryStep
+		switch {
+		case strings.HasPrefix(p.expr[p.pos:], "//"):
+			s.axis, p.pos = axisDescendant, p.pos+2
+		case p.expr[p.pos] == '/':
+			s.axis, p.pos = axisChild, p.pos+1
+		default:
+			return nil, p.errorf("expected / or //")
+		}
+
+		if err := p.step(&s); err != nil {
+			return nil, err
+		}
+		steps = append(steps, s)
+	}
+
+	if len(steps) == 0 {
+		return nil, p.errorf("empty query")
+	}
+	return steps, nil
+}
+
+func (p *queryParser) step(s *queryStep) error {
+	switch rest := p.expr[p.pos:]; {
+	case strings.HasPrefix(rest, ".."):
+		s.parent, p.pos = true, p.pos+2
+		return nil
+	case strings.HasPrefix(rest, "."):
+		s.self, p.pos = true, p.pos+1
+		return nil
+	case strings.HasPrefix(rest, "*"):
+		s.test, p.pos = anyErr, p.pos+1
+	default:
+		switch name := p.ident(); name {
+		case "class":
+			s.test = isClassErr
+		case "error":
+			s.test = anyErr
+		case "type":
+			s.test = nil
+		case "":
+			return p.errorf("expected a step")
+		default:
+			return p.errorf("unknown test %q", name)
+		}
+	}
+
+	isType := s.test == nil
+	for p.pos < len(p.expr) && p.expr[p.pos] == '[' {
+		p.pos++
+		pred, err := p.predicate(s.test, isType)
+		if err != nil {
+			return err
+		}
+		s.test, isType = pred, false
+	}
+	if isType {
+		return p.errorf("type requires a defined name, such as type[name]")
+	}
+	return nil
+}
+
+func (p *queryParser) predicate(test func(error) bool, isType bool) (func(error) bool, error) {
+	key := p.ident()
+	if key == "" {
+		return nil, p.errorf("expected a predicate")
+	}
+
+	var (
+		val    string
+		hasVal bool
+	)
+	if p.pos < len(p.expr) && p.expr[p.pos] == '=' {
+		p.pos++
+		v, err := p.quoted()
+		if err != nil {
+			return nil, err
+		}
+		val, hasVal = v, true
+	}
+	if p.pos >= len(p.expr) || p.expr[p.pos] != ']' {
+		return nil, p.errorf("expected ]")
+	}
+	p.pos++
+
+	if isType {
+		if hasVal {
+			return nil, p.errorf("type takes a defined name, not a value")
+		}
+		if e, ok := p.env.errs[key]; ok {
+			return func(err error) bool { return sameErr(err, e) }, nil
+		}
+		if t, ok := p.env.types[key]; ok {
+			return func(err error) bool { return reflect.TypeOf(err) == t }, nil
+		}
+		return nil, p.errorf("undefined name %q", key)
+	}
+
+	var pred func(error) bool
+	switch {
+	case key == "grep" && hasVal:
+		pred = func(err error) bool { return strings.Contains(err.Error(), val) }
+	case key == "shadow" && !hasVal:
+		pred = func(err error) bool {
+			c, ok := err.(*classErr)
+			return ok && c.shadow
+		}
+	case key == "name" && hasVal:
+		pred = classPred(Named(val).(*errClass).cls.is)
+	case key == "tag" && hasVal:
+		pred = classPred(func(cls *class) bool { return cls.tagged(val) })
+	case key == "prefix" && hasVal:
+		pred = classPred(func(cls *class) bool { return cls.named && inNamespace(cls.name, val) })
+	case key == "glob" && hasVal:
+		segs := strings.Split(val, ".")
+		for _, seg := range segs {
+			if _, err := path.Match(seg, ""); err != nil {
+				return nil, p.errorf("malformed glob %q", val)
+			}
+		}
+		pred = classPred(func(cls *class) bool {
+			return cls.named && globMatch(segs, strings.Split(cls.name, "."))
+		})
+	default:
+		return nil, p.errorf("unknown predicate %q", key)
+	}
+
+	return func(err error) bool { return test(err) && pred(err) }, nil
+}
+
+// classPred returns a predicate that applies f to the class of an error and
+// each of its ancestors.
+func classPred(f func(*class) bool) func(error) bool {
+	return func(err error) bool {
+		c, ok := err.(*classErr)
+		if !ok {
+			return false
+		}
+		for b := range c.classes {
+			for cls := b; cls != nil; cls = cls.parent {
+				if f(cls) {
+					return true
+				}
+			}
+		}
+		return false
+	}
+}
+
+func (p *queryParser) ident() string {
+	start := p.pos
+	for p.pos < len(p.expr) {
+		c := p.expr[p.pos]
+		if c != '_' && c != '.' && c != '-' &&
+			(c < 'a' || c > 'z') && (c < 'A' || c > 'Z') && (c < '0' || c > '9') {
+			break
+		}
+		// a leading dot is a step, not an identifier
+		if c == '.' && p.pos == start {
+			break
+		}
+		p.pos++
+	}
+	return p.expr[start:p.pos]
+}
+
+func (p *queryParser) quoted() (string, error) {
+	if p.pos >= len(p.expr) || (p.expr[p.pos] != '\'' && p.expr[p.pos] != '"') {
+		return "", p.errorf("expected a quoted string")
+	}
+	q := p.expr[p.pos]
+	end := strings.IndexByte(p.expr[p.pos+1:], q)
+	if end < 0 {
+		return "", p.errorf("unterminated string")
+	}
+	s := p.expr[p.pos+1 : p.pos+1+end]
+	p.pos += end + 2
+	return s, nil
+}
+
+func anyErr(error) bool {
+	return true
+}
+
+func isClassErr(err error) bool {
+	_, ok := err.(*classErr)
+	return ok
+}
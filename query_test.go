@@ -0,0 +1,88 @@
+package errsel
+
+import (
+	stderrors "errors"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+type queryErr struct{}
+
+func (queryErr) Error() string { return "query error" }
+
+func TestPathQuery(t *testing.T) {
+	noRows := errors.New("no rows")
+	database := Named("database", Tags("storage"))
+	lookup := database.Child("db.lookup")
+	api := NamedShadow("api")
+
+	// api#{ handle: database{ join{ lookup{ no rows }, query error } } }
+	inner := lookup.Lift(noRows)
+	err := api.Wrap(database.Lift(stderrors.Join(inner, queryErr{})), "handle")
+
+	defs := []QueryOption{DefineError("sql.ErrNoRows", noRows), DefineType("queryErr", queryErr{})}
+	cases := []struct {
+		expr string
+		ok   bool
+		er   error
+	}{
+		{"/class", true, err},
+		{"/class[shadow]", true, err},
+		{"/class[name='database']", false, nil},
+		{"//class[name='database']//type[sql.ErrNoRows]", true, noRows},
+		{"//class[name='database']/*/type[sql.ErrNoRows]", false, nil},
+		{"//class[name='database']//type[queryErr]", true, queryErr{}},
+		{"//class[name='db.lookup']/..", true, nil},
+		{"//type[sql.ErrNoRows]/../.", true, inner},
+		{"//class[tag='storage'][glob='db.*']", true, inner},
+		{"//class[prefix='db']", true, inner},
+		{"//class[name='db.lookup']/class", false, nil},
+		{"//*[grep='query error']/class", true, nil},
+		{"//error[grep='handle']", true, nil},
+		{"//class[name='missing']", false, nil},
+		{"/..", false, nil},
+	}
+
+	for _, c := range cases {
+		t.Run(c.expr, func(t *testing.T) {
+			sel, qerr := Query(c.expr, defs...)
+			if !assert.NoError(t, qerr) {
+				return
+			}
+			ok, er := sel.Traverse(err)
+			assert.Equal(t, c.ok, ok)
+			if c.er != nil {
+				assert.Equal(t, c.er, er)
+			}
+		})
+	}
+
+	assert.Equal(t, `query("//class")`, Describe(MustQuery("//class")))
+	assert.False(t, MustQuery("//error").In(nil))
+}
+
+func TestPathQueryErrors(t *testing.T) {
+	for _, expr := range []string{
+		"",
+		"class",
+		"/",
+		"/thing",
+		"/type",
+		"/type[undefined]",
+		"/type[x='y']",
+		"/class[name]",
+		"/class[name='x'",
+		"/class[name='x]",
+		"/class[color='red']",
+		"/class[glob='[']",
+	} {
+		t.Run(expr, func(t *testing.T) {
+			_, err := Query(expr)
+			assert.Error(t, err)
+		})
+	}
+
+	assert.Panics(t, func() { MustQuery("nope") })
+}
@@ -0,0 +1,152 @@
+package errsel
+
+import (
+	"reflect"
+	"strings"
+)
+
+// Redacted replaces the messages of errors lifted into sensitive classes.
+const Redacted = "[REDACTED]"
+
+// Sensitive marks a class as sensitive. The message of any error lifted
+// into it is replaced by Redacted, so that details such as connection
+// strings don't leak out through Error. Classes inherit sensitivity from
+// their parents.
+//
+//    var database = Named("database", Sensitive())
+//
+//    database.Wrap(err, "dial postgres://admin:hunter2@db") // database{ [REDACTED] }
+//
+// The full message can be rendered explicitly with Unredacted.
+func Sensitive() ClassOption {
+	return ClassOption(func(e *class) {
+		e.secret = true
+	})
+}
+
+func (e *class) sensitive() bool {
+	for cls := e; cls != nil; cls = cls.parent {
+		if cls.secret {
+			return true
+		}
+	}
+	return false
+}
+
+// Unredacted renders the message of err in full, including the messages of
+// errors lifted into sensitive classes. It should only be used where the
+// output won't reach clients, such as in server side logs.
+func Unredacted(err error) string {
+	if err == nil {
+		return ""
+	}
+	var b strings.Builder
+	renderMessage(&b, err, err.Error(), renderMode{unredacted: true}, 0)
+	return b.String()
+}
+
+// undecorated renders the message of err without the decorations of any
+// classes in its context chain.
+func undecorated(err error) string {
+	var b strings.Builder
+	renderMessage(&b, err, err.Error(), renderMode{undecorated: true}, 0)
+	return b.String()
+}
+
+// renderMode is how renderMessage departs from Error.
+type renderMode struct {
+	undecorated bool // omit the decorations of classes
+	unredacted  bool // reveal the messages of sensitive classes
+}
+
+// renderMessage writes the message of err to b, as Error would under mode.
+// msg is the message of err as Error renders it, if already known, or empty.
+//
+// Layers known to be composed of the messages beneath them are rendered
+// piece by piece. Other layers are rendered as their own message followed by
+// the rendering of the error beneath them, provided that their message ends
+// with the message beneath them; otherwise, they're rendered as they are.
+func renderMessage(b *strings.Builder, err error, msg string, mode renderMode, depth int) {
+	if max := maxChainLength.Load(); max != 0 && uint64(depth) >= max {
+		b.WriteString(err.Error())
+		return
+	}
+
+	switch e := err.(type) {
+	case *classErr:
+		decorate := !mode.undecorated && decorations.Load()
+		if decorate {
+			for cls := range e.classes {
+				if !cls.named {
+					continue
+				}
+				b.WriteString(cls.name)
+				if cls == e.cls && e.shadow {
+					b.WriteByte('#')
+				}
+				b.WriteString("{ ")
+			}
+		}
+
+		switch {
+		case e.cls.sensitive() && !mode.unredacted:
+			b.WriteString(Redacted)
+		case e.cls.soft:
+			inner := mode
+			inner.undecorated = true
+			renderMessage(b, e.err, "", inner, depth+1)
+		default:
+			renderMessage(b, e.err, "", mode, depth+1)
+		}
+
+		if decorate {
+			for cls := range e.classes {
+				if cls.named {
+					b.WriteString(" }")
+				}
+			}
+		}
+		return
+
+	case *fieldsErr:
+		renderMessage(b, e.err, msg, mode, depth+1)
+		return
+	case *templateErr:
+		renderMessage(b, e.err, msg, mode, depth+1)
+		return
+	case *valueErr:
+		renderMessage(b, e.err, msg, mode, depth+1)
+		return
+	}
+
+	if reflect.TypeOf(err) == withStackType {
+		renderMessage(b, err.(causer).Cause(), msg, mode, depth+1)
+		return
+	}
+
+	if msg == "" {
+		msg = err.Error()
+	}
+	next, multi := defaultCfg.next(err)
+	if reflect.TypeOf(err) == joinType {
+		for i, e := range multi {
+			if i > 0 {
+				b.WriteByte('\n')
+			}
+			renderMessage(b, e, "", mode, depth+1)
+		}
+		return
+	}
+	if next == nil {
+		b.WriteString(msg)
+		return
+	}
+
+	inner := next.Error()
+	if !strings.HasSuffix(msg, inner) {
+		b.WriteString(msg)
+		return
+	}
+	b.WriteString(msg[:len(msg)-len(inner)])
+	renderMessage(b, next, inner, mode, depth+1)
+}
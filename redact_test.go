@@ -0,0 +1,50 @@
+package errsel
+
+import (
+	stderrors "errors"
+	"fmt"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSensitive(t *testing.T) {
+	database := Named("database", Sensitive())
+	conn := database.Child("conn")
+	secret := Anonymous(Sensitive())
+
+	err := errors.Wrap(database.Wrap(conn.New("dial postgres://admin:hunter2@db"), "query"), "handler")
+	assert.Equal(t, "handler: database{ [REDACTED] }", err.Error())
+	assert.Equal(t, "handler: database{ query: conn{ dial postgres://admin:hunter2@db } }", Unredacted(err))
+	assert.True(t, conn.In(err))
+
+	for _, f := range Snapshot(err).Frames {
+		assert.NotContains(t, f.Message, "hunter2")
+	}
+	r := NewRegistry()
+	r.MustRegister("database", database)
+	assert.Equal(t, "handler: database{ [REDACTED] }", r.Restore(Snapshot(err)).Error())
+
+	err = secret.Wrap(errors.New("token abc"), "auth")
+	assert.Equal(t, "[REDACTED]", err.Error())
+	assert.Equal(t, "auth: token abc", Unredacted(err))
+
+	err = NamedShadow("api").Lift(err)
+	assert.Equal(t, "api#{ auth: token abc }", Unredacted(err))
+
+	// identical text elsewhere in the message is left alone
+	err = errors.Wrap(secret.New("token abc"), Redacted)
+	assert.Equal(t, "[REDACTED]: [REDACTED]", err.Error())
+	assert.Equal(t, "[REDACTED]: token abc", Unredacted(err))
+
+	err = stderrors.Join(secret.New("a"), fmt.Errorf("b: %w", database.New("c")))
+	assert.Equal(t, "a\nb: database{ c }", Unredacted(err))
+
+	SetMaxChainLength(0)
+	assert.Equal(t, "a\nb: database{ c }", Unredacted(err))
+	SetMaxChainLength(DefaultMaxChainLength)
+
+	assert.Equal(t, "plain", Unredacted(errors.New("plain")))
+	assert.Equal(t, "", Unredacted(nil))
+}
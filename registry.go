@@ -0,0 +1,130 @@
+package errsel
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// ErrDuplicateClass is returned when registering a class under a name that
+// is already taken.
+var ErrDuplicateClass = errors.New("errsel: duplicate class")
+
+// ErrSealedRegistry is returned when registering a class in a registry that
+// has been sealed.
+var ErrSealedRegistry = errors.New("errsel: sealed registry")
+
+// Registry is a central, concurrent-safe index of classes by name. Large
+// codebases can use one to discover which classes exist at runtime.
+//
+// The zero value is an empty registry ready to use.
+type Registry struct {
+	mu      sync.RWMutex
+	classes map[string]Class
+	ids     map[string]Class // classes with an identity, by identity
+	sealed  bool
+	stats   bool // whether usage is counted; see CollectStats
+}
+
+// DefaultRegistry is the registry used to resolve classes by name when no
+// other registry is provided, such as when decoding errors with errseljson.
+var DefaultRegistry = NewRegistry()
+
+// NewRegistry returns an empty registry.
+func NewRegistry() *Registry {
+	return new(Registry)
+}
+
+// Register adds cls to the registry under name, sealing it with Seal. If
+// name is already taken, it returns an error matching ErrDuplicateClass and
+// leaves the registry unchanged. If the registry has been sealed, it returns
+// an error matching ErrSealedRegistry.
+func (r *Registry) Register(name string, cls Class) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.sealed {
+		return errors.Wrapf(ErrSealedRegistry, "register %q", name)
+	}
+	if _, ok := r.classes[name]; ok {
+		return errors.Wrapf(ErrDuplicateClass, "register %q", name)
+	}
+	if r.classes == nil {
+		r.classes = make(map[string]Class)
+	}
+	r.classes[name] = Seal(cls)
+	if id, ok := IdentityOf(cls); ok {
+		if r.ids == nil {
+			r.ids = make(map[string]Class)
+		}
+		if _, ok := r.ids[id]; !ok {
+			r.ids[id] = r.classes[name]
+		}
+	}
+	if r.stats {
+		collectStats(cls)
+	}
+	return nil
+}
+
+// Seal seals the registry, so that no more classes can be registered. This
+// is typically done once initialization is complete, to prevent late
+// registrations from racing with lookups.
+func (r *Registry) Seal() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.sealed = true
+}
+
+// MustRegister is like Register, but panics on failure. It returns the
+// sealed cls, so that it can be used to initialize package level classes.
+//
+//    var conflict = registry.MustRegister("database.conflict", Named("database.conflict"))
+func (r *Registry) MustRegister(name string, cls Class) Class {
+	if err := r.Register(name, cls); err != nil {
+		panic(err)
+	}
+	return Seal(cls)
+}
+
+// Named returns a new named class, registered under its name. It panics if
+// the name is already taken.
+func (r *Registry) Named(name string, opts ...ClassOption) Class {
+	return r.MustRegister(name, Named(name, opts...))
+}
+
+// Class returns the class registered under name, if any.
+func (r *Registry) Class(name string) (Class, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	cls, ok := r.classes[name]
+	return cls, ok
+}
+
+// identity returns the first class registered with the identity id, if any.
+func (r *Registry) identity(id string) (Class, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	cls, ok := r.ids[id]
+	return cls, ok
+}
+
+// Names returns the names of every registered class, in sorted order.
+func (r *Registry) Names() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.names()
+}
+
+func (r *Registry) names() []string {
+	names := make([]string, 0, len(r.classes))
+	for name := range r.classes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
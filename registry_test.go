@@ -0,0 +1,103 @@
+package errsel
+
+import (
+	"io"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRegistry(t *testing.T) {
+	var r Registry
+	conflict := r.Named("database.conflict")
+
+	cls, ok := r.Class("database.conflict")
+	assert.True(t, ok)
+	assert.Equal(t, conflict, cls)
+
+	_, ok = r.Class("missing")
+	assert.False(t, ok)
+
+	err := r.Register("database.conflict", Anonymous())
+	assert.True(t, Error(ErrDuplicateClass).In(err))
+	assert.Panics(t, func() { r.Named("database.conflict") })
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			r.Named("class." + strconv.Itoa(i))
+			r.Names()
+		}(i)
+	}
+	wg.Wait()
+
+	names := r.Names()
+	assert.Len(t, names, 9)
+	assert.Equal(t, "class.0", names[0])
+	assert.Equal(t, "database.conflict", names[8])
+}
+
+func TestSeal(t *testing.T) {
+	native := Named("native")
+	assert.Equal(t, native, Seal(native))
+
+	typed := NamedClassOf[int]("typed")
+	sealedTyped := Seal(typed)
+	_, ok := sealedTyped.(*TypedClass[int])
+	assert.False(t, ok)
+	assert.Equal(t, sealedTyped, Seal(sealedTyped))
+	assert.True(t, sealedTyped.In(typed.LiftWith(sealedTyped.New("x"), 1)))
+
+	var r Registry
+	r.MustRegister("typed", typed)
+	cls, _ := r.Class("typed")
+	_, ok = cls.(*TypedClass[int])
+	assert.False(t, ok)
+
+	r.Seal()
+	err := r.Register("late", Named("late"))
+	assert.True(t, Error(ErrSealedRegistry).In(err))
+	assert.Panics(t, func() { r.Named("late") })
+	assert.Equal(t, []string{"typed"}, r.Names())
+}
+
+func TestRegistryStats(t *testing.T) {
+	var r Registry
+	hot := r.Named("stats.hot")
+	_ = hot.New("uncounted")
+	assert.Equal(t, []ClassStats{{Name: "stats.hot"}}, r.Stats())
+
+	r.CollectStats()
+	dead := r.Named("stats.dead")
+	typed := r.MustRegister("stats.typed", NamedClassOf[int]("stats.typed"))
+	r.MustRegister("stats.foreign", ToClass(LifterFunc(errors.WithStack), Error(io.EOF)))
+
+	before := time.Now()
+	err := hot.New("x")
+	_ = hot.Wrap(err, "y")
+	assert.True(t, hot.In(err))
+	assert.False(t, dead.In(err))
+	assert.True(t, AllOf(hot).In(err))
+	_ = typed.New("x")
+
+	stats := r.Stats()
+	assert.Len(t, stats, 4)
+	assert.Equal(t, ClassStats{Name: "stats.dead"}, stats[0])
+	assert.Equal(t, ClassStats{Name: "stats.foreign"}, stats[1])
+
+	assert.Equal(t, "stats.hot", stats[2].Name)
+	assert.Equal(t, uint64(2), stats[2].Lifts)
+	assert.Equal(t, uint64(2), stats[2].Matches)
+	assert.False(t, stats[2].LastLift.Before(before))
+	assert.False(t, stats[2].LastMatch.Before(stats[2].LastLift))
+
+	assert.Equal(t, uint64(1), stats[3].Lifts)
+	assert.Zero(t, stats[3].Matches)
+	assert.True(t, stats[3].LastMatch.IsZero())
+}
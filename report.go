@@ -0,0 +1,50 @@
+package errsel
+
+// Reporter sends errors to an error tracking service, such as Sentry.
+type Reporter interface {
+	Report(err error, meta ReportMeta)
+}
+
+// ReportMeta describes an error being reported, so that reporters can group
+// errors by class rather than by message.
+type ReportMeta struct {
+	// Selector is the description of the selector that matched the error.
+	Selector string
+
+	// Classes are the descriptions of the classes annotating the error's
+	// context chain, from the outermost inward, as returned by ClassesOf.
+	Classes []string
+
+	// Fields are the fields attached to the error, as returned by FieldsOf.
+	Fields Fields
+}
+
+// ReporterFunc is a function that can be used as a Reporter.
+type ReporterFunc func(err error, meta ReportMeta)
+
+func (f ReporterFunc) Report(err error, meta ReportMeta) {
+	f(err, meta)
+}
+
+// ReportOn returns a selector that reports an error to r every time s
+// matches it, and otherwise behaves exactly like s. Like Call, it makes it
+// impossible to forget to report an error condition; unlike Call, the whole
+// error is reported, rather than the intermediate error matched by s.
+//
+//    var unexpected = ReportOn(Not(Or(notFound, conflict)), sentryReporter)
+func ReportOn(s Selector, r Reporter) Selector {
+	desc := Describe(s)
+	return compose("report", SelectorFunc(func(err error) (bool, error) {
+		ok, er := s.Traverse(err)
+		if !ok {
+			return false, nil
+		}
+
+		meta := ReportMeta{Selector: desc, Fields: FieldsOf(err)}
+		for _, cls := range ClassesOf(err) {
+			meta.Classes = append(meta.Classes, Describe(cls))
+		}
+		r.Report(err, meta)
+		return true, er
+	}), s)
+}
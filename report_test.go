@@ -0,0 +1,36 @@
+package errsel
+
+import (
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReportOn(t *testing.T) {
+	var (
+		errs  []error
+		metas []ReportMeta
+	)
+	r := ReporterFunc(func(err error, meta ReportMeta) {
+		errs = append(errs, err)
+		metas = append(metas, meta)
+	})
+
+	a, b := Named("a"), Named("b")
+	sel := ReportOn(b, r)
+	err := errors.Wrap(a.WithFields(b.New("x"), Fields{"k": "v"}), "y")
+
+	ok, er := sel.Traverse(err)
+	assert.True(t, ok)
+	assert.True(t, b.In(er))
+	assert.False(t, sel.In(errors.New("x")))
+
+	assert.Equal(t, []error{err}, errs)
+	assert.Equal(t, []ReportMeta{{
+		Selector: "b",
+		Classes:  []string{"a", "b"},
+		Fields:   Fields{"k": "v"},
+	}}, metas)
+	assert.Equal(t, "report(b)", Describe(sel))
+}
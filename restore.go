@@ -0,0 +1,198 @@
+package errsel
+
+import (
+	"slices"
+	"strings"
+)
+
+// Restore reconstructs the error captured by snap, such as one received
+// from another process, so that selectors behave as they would have on the
+// original error. It returns nil if snap holds no frames.
+//
+// Classes are resolved by identity or name with r, so that restored errors
+// are annotated with the same classes, with the same options, as the
+// original; classes that aren't registered are restored as classes with the
+// same name and identity, which match any class with either, carrying the
+// code recorded in snap. Their names and identities aren't interned, so
+// snap may come from untrusted input. Anonymous classes without an identity
+// can't be resolved, and are dropped. Errors aren't lifted again, so
+// restoring has none of the side effects of lifting, such as default
+// messages or interceptors. Fields are attached where they were in the
+// original chain. Other errors are restored as a *SnapshotError, carrying
+// their own message; payloads aren't restored.
+func (r *Registry) Restore(snap ChainSnapshot) error {
+	if len(snap.Frames) == 0 {
+		return nil
+	}
+	err, _ := r.restore(snap.Frames, 0)
+	return err
+}
+
+// restore reconstructs the error at frames[i], and returns it along with
+// the position of the first frame that isn't beneath it.
+func (r *Registry) restore(frames []FrameSnapshot, i int) (error, int) {
+	f := frames[i]
+
+	var errs []error
+	j := i + 1
+	for j < len(frames) && frames[j].Depth == f.Depth+1 {
+		var err error
+		err, j = r.restore(frames, j)
+		errs = append(errs, err)
+	}
+
+	if f.Class == "" && len(f.Fields) > 0 && len(errs) == 1 {
+		return withFields(errs[0], f.Fields), j
+	}
+	if f.Class == "" {
+		e := &SnapshotError{Type: f.Type, Message: f.Message, Stack: f.Stack}
+		switch len(errs) {
+		case 0:
+			return e, j
+		case 1:
+			e.cause = errs[0]
+			return e, j
+		default:
+			return &snapshotJoin{msg: f.Message, errs: errs}, j
+		}
+	}
+
+	var err error
+	switch len(errs) {
+	case 0:
+		err = &SnapshotError{}
+	case 1:
+		err = errs[0]
+	default:
+		err = &snapshotJoin{errs: errs}
+	}
+	var bound []Class
+	for k, name := range f.Bound {
+		var id string
+		if k < len(f.BoundIdentities) {
+			id = f.BoundIdentities[k]
+		}
+		if cls, ok := r.resolve(restoredName(name), id, false, f.Code); ok {
+			bound = append(bound, cls)
+		}
+	}
+
+	cls, ok := r.resolve(restoredName(f.Class), f.Identity, f.Shadow, f.Code)
+	if !ok {
+		if len(bound) == 0 {
+			return err, j
+		}
+		cls, bound = bound[0], bound[1:]
+	}
+	return annotate(cls, bound, f.Shadow, err), j
+}
+
+// annotate returns err annotated with cls and any bound classes, marked as
+// shadowing if shadow is set. Unlike lifting, it has no side effects: no
+// default messages, interceptors or scrubbers are applied, no deprecation
+// warnings are emitted, and no lifts are counted. Classes not constructed
+// by this package can only be lifted into, and are.
+func annotate(cls Class, bound []Class, shadow bool, err error) error {
+	c := &classErr{cls: nativeOf(cls), err: err, shadow: shadow}
+	for _, b := range bound {
+		c.also = append(c.also, nativeOf(b))
+	}
+	if c.cls == nil || slices.Contains(c.also, nil) {
+		return Binds(cls, bound...).Lift(err)
+	}
+	return c
+}
+
+// restoredName returns the name of a class recorded by Snapshot, or "" if
+// it is anonymous.
+func restoredName(name string) string {
+	if name == "anonymous" {
+		return ""
+	}
+	return name
+}
+
+// resolve returns the class registered with the identity id, or under
+// name, or else a new class with the given name, identity and code. It
+// reports false if there is neither an identity nor a name to resolve by.
+//
+// New classes are loose, so that resolving arbitrary names and identities
+// doesn't grow the symbol table; see intern.
+func (r *Registry) resolve(name, id string, shadow bool, code *int) (Class, bool) {
+	if name == "" && id == "" {
+		return nil, false
+	}
+	if id != "" {
+		if cls, ok := r.identity(id); ok {
+			return cls, true
+		}
+	}
+	if name != "" {
+		if cls, ok := r.Class(name); ok {
+			return cls, true
+		}
+	}
+
+	var opts []ClassOption
+	if id != "" {
+		opts = append(opts, Identity(id))
+	}
+	if code != nil {
+		opts = append(opts, Code(*code))
+	}
+	e := &class{named: name != "", name: name, shadow: shadow, loose: true}
+	return e.apply(opts), true
+}
+
+// SnapshotError is a restored error that isn't a class annotation. It
+// stands in for an error of Type, which can't be reconstructed.
+type SnapshotError struct {
+	// Type is the go type of the original error.
+	Type string
+
+	// Message is the part of the original error's message that it
+	// contributed itself.
+	Message string
+
+	// Stack is the stack trace attached to the original error, if any.
+	Stack []StackFrame
+
+	cause error
+}
+
+func (e *SnapshotError) Error() string {
+	switch {
+	case e.cause == nil:
+		return e.Message
+	case e.Message == "":
+		return e.cause.Error()
+	default:
+		return e.Message + ": " + e.cause.Error()
+	}
+}
+
+// Unwrap returns the error beneath e, if any.
+func (e *SnapshotError) Unwrap() error {
+	return e.cause
+}
+
+// snapshotJoin is a restored error with multiple errors beneath it.
+type snapshotJoin struct {
+	msg  string
+	errs []error
+}
+
+func (e *snapshotJoin) Error() string {
+	if e.msg != "" {
+		return e.msg
+	}
+	msgs := make([]string, len(e.errs))
+	for i, err := range e.errs {
+		msgs[i] = err.Error()
+	}
+	return strings.Join(msgs, "\n")
+}
+
+func (e *snapshotJoin) Unwrap() []error {
+	return e.errs
+}
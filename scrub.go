@@ -0,0 +1,110 @@
+package errsel
+
+import "regexp"
+
+// Scrubber sanitizes an error message, removing things like tokens, email
+// addresses or file paths.
+type Scrubber func(msg string) string
+
+// ScrubRegexp returns a scrubber that replaces every match of re with repl,
+// as regexp.Regexp.ReplaceAllString does.
+//
+//    var tokens = errsel.ScrubRegexp(regexp.MustCompile(`token=\w+`), "token=***")
+func ScrubRegexp(re *regexp.Regexp, repl string) Scrubber {
+	return func(msg string) string {
+		return re.ReplaceAllString(msg, repl)
+	}
+}
+
+// Scrub registers scrubbers on a class, which sanitize the message of every
+// error lifted into it at lift time, before it enters the context chain.
+// Classes inherit the scrubbers of their parents, which run after their
+// own.
+//
+//    var api = Named("api", Scrub(tokens))
+//
+// Scrubbing only affects messages; the scrubbed errors remain in the chain
+// beneath, so they can still be selected. Snapshot, and so the encoders
+// built on it, and CausesOf see their messages scrubbed as well; errors
+// reached by other means, such as errors.Cause, are not.
+func Scrub(scrubbers ...Scrubber) ClassOption {
+	return ClassOption(func(e *class) {
+		e.scrub = append(e.scrub, scrubbers...)
+	})
+}
+
+func (e *class) scrubbed(err error) error {
+	for cls := e; cls != nil; cls = cls.parent {
+		err = scrub(err, cls.scrub)
+	}
+	return err
+}
+
+// scrubErr overrides the message of an error with a sanitized one.
+type scrubErr struct {
+	err       error
+	msg       string
+	scrubbers []Scrubber // that msg was sanitized with
+}
+
+func scrub(err error, scrubbers []Scrubber) error {
+	if err == nil || len(scrubbers) == 0 {
+		return err
+	}
+
+	orig := err.Error()
+	msg := scrubAll(orig, scrubbers)
+	if msg == orig {
+		return err
+	}
+
+	if s, ok := err.(*scrubErr); ok {
+		all := append(s.scrubbers[:len(s.scrubbers):len(s.scrubbers)], scrubbers...)
+		return &scrubErr{err: s.err, msg: msg, scrubbers: all}
+	}
+	return &scrubErr{err: err, msg: msg, scrubbers: scrubbers}
+}
+
+func scrubAll(msg string, scrubbers []Scrubber) string {
+	for _, f := range scrubbers {
+		msg = f(msg)
+	}
+	return msg
+}
+
+func (s *scrubErr) Error() string {
+	return s.msg
+}
+
+func (s *scrubErr) Cause() error {
+	return s.err
+}
+
+func (s *scrubErr) Unwrap() error {
+	return s.err
+}
+
+// scrubbing tracks, during a walk, the scrubbers that apply to each frame:
+// those of every scrubErr above it.
+type scrubbing struct {
+	beneath [][]Scrubber // by depth, the scrubbers of frames beneath it
+}
+
+// visit returns the scrubbers that apply to e, visited at depth.
+func (s *scrubbing) visit(e error, depth uint) []Scrubber {
+	for uint(len(s.beneath)) < depth {
+		s.beneath = append(s.beneath, nil)
+	}
+	s.beneath = s.beneath[:depth]
+
+	var above []Scrubber
+	if depth > 0 {
+		above = s.beneath[depth-1]
+	}
+	own := above
+	if se, ok := e.(*scrubErr); ok {
+		own = append(se.scrubbers[:len(se.scrubbers):len(se.scrubbers)], above...)
+	}
+	s.beneath = append(s.beneath, own)
+	return above
+}
@@ -0,0 +1,41 @@
+package errsel
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestScrub(t *testing.T) {
+	tokens := ScrubRegexp(regexp.MustCompile(`token=\w+`), "token=***")
+	emails := ScrubRegexp(regexp.MustCompile(`\w+@\w+\.com`), "<email>")
+	upper := Scrubber(strings.ToUpper)
+
+	api := Named("api", Scrub(tokens))
+	auth := api.Child("auth", Scrub(emails))
+
+	root := errors.New("bad token=abc123 for bob@example.com")
+	err := errors.Wrap(auth.Wrap(root, "login"), "handler")
+
+	assert.Equal(t, "handler: auth{ login: bad token=*** for <email> }", err.Error())
+	assert.True(t, Error(root).In(err))
+	assert.True(t, api.In(err))
+
+	err = LifterFunc(Named("shout").Lift).Scrub(upper).New("quiet")
+	assert.Equal(t, "shout{ QUIET }", err.Error())
+
+	for _, e := range CausesOf(err) {
+		assert.NotContains(t, e.Error(), "abc123")
+	}
+	for _, f := range Snapshot(err).Frames {
+		assert.NotContains(t, f.Message, "abc123")
+		assert.NotContains(t, f.Message, "bob@example.com")
+	}
+	assert.Equal(t, err.Error(), DefaultRegistry.Restore(Snapshot(err)).Error())
+
+	clean := errors.New("nothing to see")
+	assert.Equal(t, clean, api.Lift(clean).(*classErr).err)
+}
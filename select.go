@@ -1,9 +1,15 @@
 package errsel
 
 import (
+	"context"
+	"fmt"
+	"math/rand/v2"
 	"reflect"
+	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
 )
 
 // TODO: the type shadowing interfaces should be removed,
@@ -44,6 +50,47 @@ type Selector interface {
 
 var _ Selector = new(SelectorFunc)
 
+// Querier is implemented by selectors that can report whether they match
+// an error along with the intermediate error that they matched, in the
+// order of the comma-ok idiom. Every selector and class constructed by this
+// package implements it; it is kept off Selector so that existing Selector
+// implementations outside this package continue to satisfy it.
+//
+//    if q, ok := sel.(errsel.Querier); ok {
+//        if matched, ok := q.Query(err); ok {
+//            // inspect matched
+//        }
+//    }
+type Querier interface {
+	Query(err error) (matched error, ok bool)
+}
+
+var _ Querier = new(SelectorFunc)
+
+// queryOf reports whether s matches err, along with the intermediate error
+// that it matched.
+func queryOf(s Selector, err error) (error, bool) {
+	ok, er := s.Traverse(err)
+	return er, ok
+}
+
+// Matcher is implemented by selectors that can decide whether they match
+// an error without reporting the intermediate error that matched, which is
+// cheaper for some selectors than Traverse. Classes and the combinators of
+// this package implement it, and prefer it when evaluating their inputs.
+type Matcher interface {
+	MatchBool(err error) bool
+}
+
+// matchBool reports whether s matches err, using MatchBool if s is a
+// Matcher.
+func matchBool(s Selector, err error) bool {
+	if m, ok := s.(Matcher); ok {
+		return m.MatchBool(err)
+	}
+	return s.In(err)
+}
+
 type SelectorFunc func(error) (bool, error)
 
 func (f SelectorFunc) Traverse(err error) (bool, error) {
@@ -60,6 +107,90 @@ func (f SelectorFunc) Is(err error) error {
 	return er
 }
 
+func (f SelectorFunc) Query(err error) (error, bool) {
+	ok, er := f(err)
+	return er, ok
+}
+
+// node is a selector composed from other selectors, or a leaf selector
+// with a description. Recording the structure of a composition allows
+// selector trees to be inspected after the fact.
+type node struct {
+	Selector
+	op    string
+	args  []Selector
+	desc  string
+	match func(error) bool // optional fast path for In
+}
+
+func compose(op string, sel Selector, args ...Selector) Selector {
+	return &node{
+		Selector: sel,
+		op:       op,
+		args:     args,
+	}
+}
+
+// composeRoot is like compose, for compositions that match as Root(f) does.
+// Such nodes decide In by calling f directly.
+func composeRoot(op string, f func(error) bool, args ...Selector) Selector {
+	return &node{
+		Selector: Root(f),
+		op:       op,
+		args:     args,
+		match:    f,
+	}
+}
+
+func (n *node) MatchBool(err error) bool {
+	if n.match != nil {
+		return n.match(err)
+	}
+	return matchBool(n.Selector, err)
+}
+
+func (n *node) Query(err error) (error, bool) {
+	return queryOf(n, err)
+}
+
+func (n *node) In(err error) bool {
+	return n.MatchBool(err)
+}
+
+func describe(desc string, sel Selector) Selector {
+	return &node{
+		Selector: sel,
+		desc:     desc,
+	}
+}
+
+// String describes the selector tree rooted at n, such as
+// and(database, not(conflict)).
+func (n *node) String() string {
+	if n.op == "" {
+		return n.desc
+	}
+
+	var args []string
+	if n.desc != "" {
+		args = append(args, n.desc)
+	}
+	for _, s := range n.args {
+		args = append(args, Describe(s))
+	}
+	return n.op + "(" + strings.Join(args, ", ") + ")"
+}
+
+// Describe returns a human readable description of a selector, suitable for
+// logging. Selectors that implement fmt.Stringer describe themselves; any
+// other selector is described as "selector".
+func Describe(s Selector) string {
+	if str, ok := s.(fmt.Stringer); ok {
+		return str.String()
+	}
+	return "selector"
+}
+
 // And returns a selector that will only match if all input selectors
 // match. It will always return the error it was called with on a match,
 // and nil otherwise.
@@ -68,89 +199,232 @@ func (f SelectorFunc) Is(err error) error {
 func And(ss ...Selector) Selector {
 	// TODO: stream fusion would require selectors to be able to return
 	// their predicate func, if they were created from one.
-	return Root(func(err error) bool {
+	return composeRoot("and", func(err error) bool {
 		accum := true
 		for _, s := range ss {
-			ok, _ := s.Traverse(err)
-			accum = accum && ok
+			accum = matchBool(s, err) && accum
 		}
 		return accum
-	})
+	}, ss...)
 }
 
 // AndL is strict in s and lazy in l.
 //
 // otherwise it's like And
 func AndL(s, l Selector) Selector {
-	return Root(func(err error) bool {
-		if !s.In(err) {
+	return composeRoot("andl", func(err error) bool {
+		if !matchBool(s, err) {
 			return false
 		}
-		return l.In(err)
-	})
+		return matchBool(l, err)
+	}, s, l)
 }
 
-// AndC behaves like And, except that input selectors will be evaluated
-// concurrently.
-func AndC(ss ...Selector) Selector {
-	return Root(func(err error) bool {
-		var (
-			accum = true
-			mu    sync.Mutex
-			wg    sync.WaitGroup
-		)
+// AndSC behaves like And, except that it is lazy: input selectors are
+// evaluated in order, and evaluation stops at the first that doesn't match.
+// Side effects of the remaining selectors (such as those of Call) will
+// not fire.
+func AndSC(ss ...Selector) Selector {
+	return composeRoot("andsc", func(err error) bool {
 		for _, s := range ss {
-			wg.Add(1)
-			go func(s Selector) {
-				ok, _ := s.Traverse(err)
-				mu.Lock()
-				accum = accum && ok
-				mu.Unlock()
-				wg.Done()
-			}(s)
+			if !matchBool(s, err) {
+				return false
+			}
 		}
+		return true
+	}, ss...)
+}
 
-		wg.Wait()
-		return accum
-	})
+// AndC behaves like And, except that input selectors will be evaluated
+// concurrently. It returns as soon as any input selector doesn't match,
+// cancelling the context of any that are still running.
+func AndC(ss ...Selector) Selector {
+	return composeRoot("andc", func(err error) bool {
+		return concurrently(ss, err, false, len(ss))
+	}, ss...)
+}
+
+// AndCN behaves like AndC, except that at most n input selectors will be
+// evaluated at once.
+func AndCN(n int, ss ...Selector) Selector {
+	sel := composeRoot("andc", func(err error) bool {
+		return concurrently(ss, err, false, n)
+	}, ss...)
+	sel.(*node).desc = strconv.Itoa(n)
+	return sel
 }
 
 // Or returns a selector that will match if any of the input selectors
 // match. It will always return the error it was called with on a match,
 // and nil otherwise.
 func Or(ss ...Selector) Selector {
-	return Root(func(err error) bool {
+	return composeRoot("or", func(err error) bool {
 		var accum bool
 		for _, s := range ss {
-			ok, _ := s.Traverse(err)
-			accum = accum || ok
+			accum = matchBool(s, err) || accum
 		}
 		return accum
-	})
+	}, ss...)
+}
+
+// OrSC behaves like Or, except that it is lazy: input selectors are
+// evaluated in order, and evaluation stops at the first that matches.
+// Side effects of the remaining selectors (such as those of Call) will
+// not fire.
+func OrSC(ss ...Selector) Selector {
+	return composeRoot("orsc", func(err error) bool {
+		for _, s := range ss {
+			if matchBool(s, err) {
+				return true
+			}
+		}
+		return false
+	}, ss...)
 }
 
 // OrC behaves like Or, except that input selectors will be evaluated
-// concurrently.
+// concurrently. It returns as soon as any input selector matches,
+// cancelling the context of any that are still running.
 func OrC(ss ...Selector) Selector {
-	return Root(func(err error) bool {
-		var (
-			accum bool
-			mu    sync.Mutex
-			wg    sync.WaitGroup
-		)
+	return composeRoot("orc", func(err error) bool {
+		return concurrently(ss, err, true, len(ss))
+	}, ss...)
+}
+
+// OrCN behaves like OrC, except that at most n input selectors will be
+// evaluated at once.
+func OrCN(n int, ss ...Selector) Selector {
+	sel := composeRoot("orc", func(err error) bool {
+		return concurrently(ss, err, true, n)
+	}, ss...)
+	sel.(*node).desc = strconv.Itoa(n)
+	return sel
+}
+
+// concurrently evaluates every selector in ss against err, at most n at a
+// time. It returns decisive as soon as any selector's result is decisive,
+// and the opposite if none are.
+//
+// Selectors still running when the result is decided are not waited for;
+// if they are ContextSelectors, their context is cancelled. Selectors that
+// haven't started yet never will.
+func concurrently(ss []Selector, err error, decisive bool, n int) bool {
+	if n <= 0 || n > len(ss) {
+		n = len(ss)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	type result struct {
+		ok bool
+		p  *panicked
+	}
+	var (
+		work    = make(chan Selector)
+		results = make(chan result, len(ss))
+	)
+
+	traverse := func(s Selector) (r result) {
+		defer recoverInto(&r.p)
+		r.ok, _ = TraverseContext(ctx, s, err)
+		return r
+	}
+	for i := 0; i < n; i++ {
+		go func() {
+			for s := range work {
+				results <- traverse(s)
+			}
+		}()
+	}
+
+	go func() {
+		defer close(work)
 		for _, s := range ss {
-			wg.Add(1)
-			go func(s Selector) {
-				ok, _ := s.Traverse(err)
-				mu.Lock()
-				accum = accum || ok
-				mu.Unlock()
-				wg.Done()
-			}(s)
-		}
-		wg.Wait()
-		return accum
-	})
+			select {
+			case work <- s:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	for range ss {
+		r := <-results
+		r.p.raise()
+		if r.ok == decisive {
+			return decisive
+		}
+	}
+	return !decisive
+}
+
+// Xor returns a selector that will match if exactly one of a and b match.
+// It will always return the error it was called with on a match, and nil
+// otherwise.
+func Xor(a, b Selector) Selector {
+	return composeRoot("xor", func(err error) bool {
+		return matchBool(a, err) != matchBool(b, err)
+	}, a, b)
+}
+
+// AtLeast returns a selector that will match if at least n of the input
+// selectors match. It will always return the error it was called with on a
+// match, and nil otherwise.
+//
+// AtLeast evaluates input selectors in order, and stops as soon as the
+// result is decided.
+func AtLeast(n int, ss ...Selector) Selector {
+	sel := composeRoot("atleast", func(err error) bool {
+		var matched int
+		for i, s := range ss {
+			if matched >= n || matched+len(ss)-i < n {
+				break
+			}
+			if matchBool(s, err) {
+				matched++
+			}
+		}
+		return matched >= n
+	}, ss...)
+	sel.(*node).desc = strconv.Itoa(n)
+	return sel
+}
+
+// Weighted pairs a selector with its weight, for use with Score.
+type Weighted struct {
+	Selector Selector
+	Weight   int
+}
+
+// Score returns a selector that sums the weights of every input selector
+// that matches, and will match if the total exceeds threshold. It will
+// always return the error it was called with on a match, and nil otherwise.
+//
+//    var vendorTimeout = Score([]Weighted{
+//        {Grep("timeout"), 1},
+//        {Type(&net.OpError{}), 2},
+//        {Error(context.Canceled), 3},
+//    }, 2)
+//
+// Every input selector is evaluated, in order.
+func Score(weights []Weighted, threshold int) Selector {
+	ss := make([]Selector, len(weights))
+	for i, w := range weights {
+		ss[i] = w.Selector
+	}
+
+	sel := composeRoot("score", func(err error) bool {
+		var total int
+		for _, w := range weights {
+			if matchBool(w.Selector, err) {
+				total += w.Weight
+			}
+		}
+		return total > threshold
+	}, ss...)
+	sel.(*node).desc = strconv.Itoa(threshold)
+	return sel
 }
 
 // Not returns a selector that will invert the input selector's result.
@@ -159,8 +433,38 @@ func Not(s Selector) Selector {
 	// we want to return an f(err) bool, error
 	// that inverts the bool
 	// we don't want to mess with the error output
-	return Root(func(err error) bool {
-		return !s.In(err)
+	return composeRoot("not", func(err error) bool {
+		return !matchBool(s, err)
+	}, s)
+}
+
+// Wraps returns a selector that will match if an error matching outer
+// appears above one matching inner in an error's context chain, returning
+// the error matched by outer. Unlike And, ordering matters:
+//
+//    Wraps(api, storage).In(api.Lift(storage.New("oh no"))) // true
+//    Wraps(api, storage).In(storage.Lift(api.New("oh no"))) // false
+//
+// Errors are matched by outer and inner themselves, rather than anywhere in
+// their context chains, and shadowing is not respected.
+func Wraps(outer, inner Selector) Selector {
+	return compose("wraps", wraps(outer, inner, Lens(1)), outer, inner)
+}
+
+// WrapsWithin is like Wraps, but only matches if at most gap other class
+// annotations appear between the errors matched by outer and inner.
+// Unannotated frames, such as those added by errors.Wrap, don't count
+// towards the gap.
+func WrapsWithin(outer, inner Selector, gap uint) Selector {
+	sel := compose("wraps", wraps(outer, inner, Lens(1), ClassDepth(gap+1)), outer, inner)
+	sel.(*node).desc = strconv.FormatUint(uint64(gap), 10)
+	return sel
+}
+
+func wraps(outer, inner Selector, opts ...TraverseOption) Selector {
+	isOuter, beneath := atFrame(outer), Causes(atFrame(inner), opts...)
+	return Causes(func(err error) bool {
+		return isOuter(err) && beneath.In(err)
 	})
 }
 
@@ -169,9 +473,36 @@ func Not(s Selector) Selector {
 //
 // Any provided traverse options will scope to causes.
 func Error(err error, opts ...TraverseOption) Selector {
-	return Causes(func(er error) bool {
+	return describe("error("+quoteErr(err)+")", Causes(func(er error) bool {
 		return err == er
-	}, opts...)
+	}, opts...))
+}
+
+// Is returns a selector that will match if any error in an error's context
+// chain is equivalent to target, following the semantics of the standard
+// library's errors.Is: an error is equivalent if it is equal to target, or
+// if it implements an Is(error) bool method that reports true for target.
+//
+// Any provided traverse options will scope to causes.
+func Is(target error, opts ...TraverseOption) Selector {
+	eq := target != nil && reflect.TypeOf(target).Comparable()
+	return describe("is("+quoteErr(target)+")", Causes(func(err error) bool {
+		if eq && err == target {
+			return true
+		}
+		if x, ok := err.(interface{ Is(error) bool }); ok {
+			return x.Is(target)
+		}
+		return false
+	}, opts...))
+}
+
+// quoteErr quotes the message of err for use in a description.
+func quoteErr(err error) string {
+	if err == nil {
+		return "nil"
+	}
+	return strconv.Quote(err.Error())
 }
 
 // Type returns a selector that will match if the provided type occurs
@@ -180,17 +511,154 @@ func Error(err error, opts ...TraverseOption) Selector {
 // Any provided traverse options will scope to causes.
 func Type(t interface{}, opts ...TraverseOption) Selector {
 	T := reflect.TypeOf(t)
-	return Causes(func(err error) bool {
+	return describe(fmt.Sprintf("type(%v)", T), Causes(func(err error) bool {
 		return reflect.TypeOf(err) == T
-	}, opts...)
+	}, opts...))
 }
 
 // Grep returns a selector that will match if the provided string is a
 // substring in an error's concatenated Error() output.
+//
+// Where the message of a layer is known to be built from the messages
+// beneath it, as with classes and stack traces, Grep searches the pieces in
+// place rather than concatenating them.
 func Grep(str string) Selector {
-	return Root(func(err error) bool {
-		idx := strings.Index(err.Error(), str)
-		return idx != -1
+	return describe(fmt.Sprintf("grep(%q)", str), Root(newGrep(str).match))
+}
+
+// Branches is a selector composed of ordered branches, which can report
+// which of them matched.
+type Branches struct {
+	*node
+	ss []Selector
+}
+
+// First returns a selector that will match if any of the input selectors
+// match, evaluating them in order and stopping at the first match. Unlike
+// Or, it will return the intermediate error matched by that selector.
+//
+// Its Match method additionally reports the index of the matching branch,
+// which can be used to build dispatch tables:
+//
+//    handlers := []func(error){handleConflict, handleNotFound}
+//    if i, matched := First(conflict, notFound).Match(err); i >= 0 {
+//        handlers[i](matched)
+//    }
+func First(ss ...Selector) *Branches {
+	b := &Branches{ss: ss}
+	b.node = compose("first", SelectorFunc(func(err error) (bool, error) {
+		i, matched := b.Match(err)
+		return i >= 0, matched
+	}), ss...).(*node)
+	return b
+}
+
+// Match returns the index of the first branch that matches err, and the
+// intermediate error that it matched. If no branch matches, it returns -1
+// and nil.
+func (b *Branches) Match(err error) (int, error) {
+	for i, s := range b.ss {
+		if ok, er := s.Traverse(err); ok {
+			return i, er
+		}
+	}
+	return -1, nil
+}
+
+// Case bundles a selector with the handler to call when it matches.
+type Case struct {
+	Selector Selector
+	Handler  func(error)
+}
+
+// Cases returns a selector that formalizes an error routing table. Cases
+// are evaluated in order, and the handler of only the first matching case
+// is called with the intermediate error that its selector matched; later
+// cases are not evaluated at all.
+//
+//    var route = Cases(
+//        Case{conflict, handleConflict},
+//        Case{notFound, handle404},
+//    )
+//
+//    if i, _ := route.Match(err); i < 0 {
+//        handle500(err)
+//    }
+//
+// Match reports the index of the case that fired.
+func Cases(cs ...Case) *Branches {
+	ss := make([]Selector, len(cs))
+	for i, c := range cs {
+		c := c
+		ss[i] = compose("case", SelectorFunc(func(err error) (bool, error) {
+			ok, er := c.Selector.Traverse(err)
+			if ok && c.Handler != nil {
+				c.Handler(er)
+			}
+			return ok, er
+		}), c.Selector)
+	}
+
+	b := First(ss...)
+	b.op = "cases"
+	return b
+}
+
+// Map returns a selector that will match if s matches, but will return the
+// intermediate error that s matched as rewritten by f. This can be used to
+// build error translation layers out of selectors:
+//
+//    var toPublic = Map(internalConflict, func(err error) error {
+//        return publicConflict.Wrap(err, "request conflicts with current state")
+//    })
+//
+//    if ok, public := toPublic.Traverse(err); ok {
+//        return public
+//    }
+func Map(s Selector, f func(error) error) Selector {
+	return compose("map", SelectorFunc(func(err error) (bool, error) {
+		ok, er := s.Traverse(err)
+		if !ok {
+			return false, nil
+		}
+		return true, f(er)
+	}), s)
+}
+
+// Filter returns a selector that will match only if s matches, and pred
+// approves of the intermediate error that s matched. This can be used to
+// refine class matches by inspecting the matched error.
+//
+// Only the intermediate that s matched is considered; if pred vetoes it,
+// Filter doesn't match even if s would have matched something deeper.
+func Filter(s Selector, pred func(matched error) bool) Selector {
+	return compose("filter", SelectorFunc(func(err error) (bool, error) {
+		ok, er := s.Traverse(err)
+		if !ok || !pred(er) {
+			return false, nil
+		}
+		return true, er
+	}), s)
+}
+
+// Recover returns a lifter for use at API boundaries: if s matches an error,
+// the error is replaced by the result of calling replacement with the
+// intermediate error that s matched. If replacement returns nil, the error
+// is suppressed entirely. Errors that s doesn't match pass through as is.
+//
+//    var boundary = Recover(notFound, func(error) error {
+//        return nil // a missing row is not an error for this endpoint
+//    })
+//
+//    func handler() error {
+//        return boundary.Lift(lookup())
+//    }
+func Recover(s Selector, replacement func(error) error) LifterFunc {
+	return LifterFunc(func(err error) error {
+		if ok, er := s.Traverse(err); ok {
+			return replacement(er)
+		}
+		return err
 	})
 }
 
@@ -211,6 +679,17 @@ func Call(f func(error), s Selector) Selector {
 	})
 }
 
+// Tap returns a selector that will call the provided function with every
+// error it inspects, whether or not the provided selector matches, and
+// otherwise behaves exactly like the provided selector. Tap and Call can be
+// used together to count inspections versus matches.
+func Tap(f func(error), s Selector) Selector {
+	return compose("tap", SelectorFunc(func(err error) (bool, error) {
+		f(err)
+		return s.Traverse(err)
+	}), s)
+}
+
 // Once is an idempotent alternative to Call. For any (n > 0) times that
 // the returned selector has matched, the provided f is guaranteed to have
 // executed exactly once.
@@ -227,6 +706,84 @@ func Once(f func(error), s Selector) Selector {
 	})
 }
 
+// Cooldown is a selector whose side effect fires at most once per cooldown
+// period, and which can be reset.
+type Cooldown struct {
+	Selector
+
+	mu    sync.Mutex
+	fired bool
+	last  time.Time
+}
+
+// OnceEvery is an alternative to Once for long running processes. The
+// provided f is executed the first time that the returned selector matches,
+// and then again on the first match after each cooldown period d has
+// elapsed. If d is 0, f is executed once until the returned Cooldown is
+// reset.
+func OnceEvery(d time.Duration, f func(error), s Selector) *Cooldown {
+	c := new(Cooldown)
+	c.Selector = Root(func(err error) bool {
+		ok, er := s.Traverse(err)
+		if ok && c.fire(d) {
+			f(er)
+		}
+		return ok
+	})
+	return c
+}
+
+func (c *Cooldown) fire(d time.Duration) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	if c.fired && (d <= 0 || now.Sub(c.last) < d) {
+		return false
+	}
+	c.fired, c.last = true, now
+	return true
+}
+
+func (c *Cooldown) Query(err error) (error, bool) {
+	return queryOf(c, err)
+}
+
+// Reset allows the side effect to fire again on the next match, regardless
+// of the cooldown period.
+func (c *Cooldown) Reset() {
+	c.mu.Lock()
+	c.fired = false
+	c.mu.Unlock()
+}
+
+// Every is a sampled alternative to Call. The provided f is executed on
+// every n-th time that the returned selector has matched, starting with the
+// n-th. If n is 0, f is never executed.
+func Every(n uint, f func(error), s Selector) Selector {
+	var count uint64
+	return Root(func(err error) bool {
+		ok, er := s.Traverse(err)
+		if ok && n > 0 && atomic.AddUint64(&count, 1)%uint64(n) == 0 {
+			f(er)
+		}
+		return ok
+	})
+}
+
+// CallSampled is a sampled alternative to Call. The provided f is executed
+// for a random fraction p of the times that the returned selector matches;
+// if p is 0 it never executes, and if p is 1 it always does.
+func CallSampled(p float64, f func(error), s Selector) Selector {
+	return Root(func(err error) bool {
+		ok, er := s.Traverse(err)
+		if ok && rand.Float64() < p {
+			f(er)
+		}
+		return ok
+	})
+}
+
 // Mask returns a selector that masks the provided selector with a SelectorFunc
 // wrapper. This can be useful if you want to export a class for use as a
 // selector, while disallowing the creation of new error instances.
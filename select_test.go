@@ -1,8 +1,11 @@
 package errsel
 
 import (
+	"context"
 	"fmt"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/pkg/errors"
 	"github.com/stretchr/testify/assert"
@@ -190,3 +193,392 @@ func BenchmarkCausesE(b *testing.B) {
 		//_ = isTip(ErrSomeErr)
 	}
 }
+
+type tempErr struct{ code int }
+
+func (e tempErr) Error() string { return fmt.Sprintf("temporary: %d", e.code) }
+
+func (e tempErr) Is(target error) bool {
+	t, ok := target.(tempErr)
+	return ok && t.code == 0
+}
+
+func TestIs(t *testing.T) {
+	sentinel := errors.New("sentinel")
+	err := Named("net").Wrap(fmt.Errorf("dial: %w", tempErr{code: 11}), "fetch")
+
+	ok, er := Is(tempErr{}).Traverse(err)
+	assert.True(t, ok)
+	assert.Equal(t, tempErr{code: 11}, er)
+
+	assert.True(t, Is(tempErr{code: 11}).In(err))
+	assert.False(t, Is(tempErr{code: 12}).In(err))
+	assert.False(t, Is(sentinel).In(err))
+	assert.True(t, Is(sentinel).In(errors.Wrap(sentinel, "wrapped")))
+	assert.False(t, Error(tempErr{}).In(err))
+}
+
+func TestQuery(t *testing.T) {
+	matched, ok := okayStuff.(Querier).Query(ErrSomeErr)
+	assert.True(t, ok)
+	assert.Equal(t, ErrInter, matched)
+
+	matched, ok = Mask(Anonymous()).(Querier).Query(ErrSomeErr)
+	assert.False(t, ok)
+	assert.Nil(t, matched)
+
+	for _, sel := range []Selector{
+		Or(okayStuff), Seal(okayStuff), NewBoundary("b"), ClassOf[int](),
+		OnceEvery(time.Hour, func(error) {}, okayStuff), WithTimeout(time.Second, okayStuff), ContextSelectorFunc(func(context.Context, error) (bool, error) { return false, nil }),
+	} {
+		_, ok := sel.(Querier)
+		assert.True(t, ok, Describe(sel))
+	}
+}
+
+func TestShortCircuit(t *testing.T) {
+	var calls int
+	count := func(error) { calls++ }
+	yes, no := Grep(""), Not(Grep(""))
+
+	assert.False(t, AndSC(yes, no, Call(count, yes)).In(ErrCause))
+	assert.Equal(t, 0, calls)
+	assert.True(t, AndSC(yes, Call(count, yes)).In(ErrCause))
+	assert.Equal(t, 1, calls)
+
+	assert.True(t, OrSC(no, yes, Call(count, yes)).In(ErrCause))
+	assert.Equal(t, 1, calls)
+	assert.False(t, OrSC(no, Call(count, no)).In(ErrCause))
+	assert.Equal(t, 1, calls)
+
+	assert.False(t, And(no, Call(count, yes)).In(ErrCause))
+	assert.Equal(t, 2, calls)
+
+	x := Explain(OrSC(no, yes, Call(count, yes)), ErrCause)
+	assert.True(t, x.Matched)
+	assert.True(t, x.Children[2].Skipped)
+	assert.Equal(t, 2, calls)
+}
+
+func TestXorAtLeast(t *testing.T) {
+	yes, no := Grep(""), Not(Grep(""))
+
+	assert.True(t, Xor(yes, no).In(ErrCause))
+	assert.True(t, Xor(no, yes).In(ErrCause))
+	assert.False(t, Xor(yes, yes).In(ErrCause))
+	assert.False(t, Xor(no, no).In(ErrCause))
+
+	assert.True(t, AtLeast(0).In(ErrCause))
+	assert.True(t, AtLeast(2, yes, no, yes).In(ErrCause))
+	assert.False(t, AtLeast(3, yes, no, yes).In(ErrCause))
+	assert.True(t, AtLeast(2, badStuff, okayStuff, Type(ErrCause)).In(ErrInter))
+
+	var calls int
+	count := func(error) { calls++ }
+	assert.True(t, AtLeast(1, yes, Call(count, yes)).In(ErrCause))
+	assert.False(t, AtLeast(2, no, no, Call(count, yes)).In(ErrCause))
+	assert.Equal(t, 0, calls)
+
+	assert.Equal(t, "atleast(2, xor(grep(\"\"), not(grep(\"\"))))", Describe(AtLeast(2, Xor(yes, no))))
+}
+
+func TestWraps(t *testing.T) {
+	api, service, storage := Named("api"), Named("service"), Named("storage")
+
+	err := api.Lift(errors.Wrap(service.Lift(errors.Wrap(storage.New("oh no"), "query")), "handle"))
+
+	ok, er := Wraps(api, storage).Traverse(err)
+	assert.True(t, ok)
+	assert.Equal(t, err, er)
+	assert.True(t, Wraps(service, storage).In(err))
+	assert.False(t, Wraps(storage, api).In(err))
+	assert.False(t, Wraps(api, api).In(err))
+	assert.True(t, Wraps(api, Error(errors.Cause(err))).In(err))
+
+	assert.True(t, WrapsWithin(api, service, 0).In(err))
+	assert.False(t, WrapsWithin(api, storage, 0).In(err))
+	assert.True(t, WrapsWithin(api, storage, 1).In(err))
+
+	assert.Equal(t, "wraps(api, storage)", Describe(Wraps(api, storage)))
+	assert.Equal(t, "wraps(1, api, storage)", Describe(WrapsWithin(api, storage, 1)))
+}
+
+func TestFirst(t *testing.T) {
+	first := First(goodStuff, okayStuff, badStuff)
+
+	i, matched := first.Match(ErrInter)
+	assert.Equal(t, 1, i)
+	assert.Equal(t, ErrInter, matched)
+
+	i, matched = first.Match(ErrSomeErr)
+	assert.Equal(t, 0, i)
+	assert.Equal(t, ErrSomeErr, matched)
+
+	i, matched = first.Match(errors.New("nope"))
+	assert.Equal(t, -1, i)
+	assert.Nil(t, matched)
+
+	matched, ok := First(Grep("nope"), badStuff).Query(ErrSomeErr)
+	assert.True(t, ok)
+	assert.Equal(t, ErrCause, matched)
+
+	x := Explain(First(Grep("nope"), badStuff, okayStuff), ErrSomeErr)
+	assert.True(t, x.Matched)
+	assert.Equal(t, ErrCause, x.Error)
+	assert.True(t, x.Children[2].Skipped)
+	assert.Equal(t, `first(grep("nope"), anonymous)`, Describe(First(Grep("nope"), badStuff)))
+}
+
+func TestMap(t *testing.T) {
+	public := Named("public")
+	toPublic := Map(okayStuff, func(err error) error {
+		return public.Wrap(err, "translated")
+	})
+
+	ok, er := toPublic.Traverse(ErrSomeErr)
+	assert.True(t, ok)
+	assert.True(t, public.In(er))
+	assert.True(t, Error(ErrInter).In(er))
+
+	ok, er = toPublic.Traverse(errors.New("nope"))
+	assert.False(t, ok)
+	assert.Nil(t, er)
+}
+
+func TestRecover(t *testing.T) {
+	public := Named("public")
+	boundary := Recover(Or(okayStuff, badStuff), func(err error) error {
+		if err == ErrCause {
+			return nil
+		}
+		return public.New("sanitized")
+	})
+
+	assert.Nil(t, boundary.Lift(ErrCause))
+	assert.Nil(t, boundary.Lift(nil))
+	assert.True(t, public.In(boundary.Lift(ErrSomeErr)))
+	assert.Equal(t, "public{ sanitized }", boundary.Lift(ErrSomeErr).Error())
+
+	other := errors.New("other")
+	assert.Equal(t, other, boundary.Lift(other))
+}
+
+func TestTap(t *testing.T) {
+	var inspected, matched int
+	sel := Tap(func(error) { inspected++ }, Call(func(error) { matched++ }, okayStuff))
+
+	ok, er := sel.Traverse(ErrSomeErr)
+	assert.True(t, ok)
+	assert.Equal(t, ErrSomeErr, er)
+	assert.False(t, sel.In(ErrCause))
+
+	assert.Equal(t, 2, inspected)
+	assert.Equal(t, 1, matched)
+}
+
+func TestFilter(t *testing.T) {
+	isCause := func(err error) bool { return err == ErrCause }
+
+	ok, er := Filter(badStuff, isCause).Traverse(ErrSomeErr)
+	assert.True(t, ok)
+	assert.Equal(t, ErrCause, er)
+
+	ok, er = Filter(okayStuff, isCause).Traverse(ErrSomeErr)
+	assert.False(t, ok)
+	assert.Nil(t, er)
+
+	assert.False(t, Filter(Named("nope"), func(error) bool { return true }).In(ErrSomeErr))
+}
+
+func TestCases(t *testing.T) {
+	var fired []string
+	route := Cases(
+		Case{goodStuff, func(error) { fired = append(fired, "good") }},
+		Case{okayStuff, func(error) { fired = append(fired, "okay") }},
+		Case{badStuff, func(error) { fired = append(fired, "bad") }},
+	)
+
+	i, matched := route.Match(ErrInter)
+	assert.Equal(t, 1, i)
+	assert.Equal(t, ErrInter, matched)
+	assert.Equal(t, []string{"okay"}, fired)
+
+	assert.True(t, route.In(ErrSomeErr))
+	assert.Equal(t, []string{"okay", "good"}, fired)
+
+	i, _ = route.Match(errors.New("nope"))
+	assert.Equal(t, -1, i)
+	assert.Len(t, fired, 2)
+
+	assert.Equal(t, "cases(case(anonymous), case(anonymous), case(anonymous))", Describe(route))
+}
+
+func TestScore(t *testing.T) {
+	weights := []Weighted{
+		{Grep("asdfjknaksjdfn"), 1},
+		{okayStuff, 2},
+		{goodStuff, 4},
+		{Root(func(error) bool { return false }), 8},
+	}
+
+	assert.True(t, Score(weights, 6).In(ErrSomeErr))
+	assert.False(t, Score(weights, 7).In(ErrSomeErr))
+	assert.True(t, Score(weights, 2).In(ErrInter))
+	assert.False(t, Score(weights, 3).In(ErrInter))
+	assert.False(t, Score(weights, 0).In(errors.New("nope")))
+	assert.False(t, Score(nil, 0).In(ErrSomeErr))
+	assert.Equal(t, "score(6, grep(\"asdfjknaksjdfn\"), anonymous, anonymous, selector)", Describe(Score(weights, 6)))
+}
+
+func slow(d time.Duration, s Selector) Selector {
+	return SelectorFunc(func(err error) (bool, error) {
+		time.Sleep(d)
+		return s.Traverse(err)
+	})
+}
+
+func TestConcurrentEarlyExit(t *testing.T) {
+	yes, no := Grep(""), Not(Grep(""))
+
+	// blocks until cancelled; without early exit these would never return
+	blocking := ContextSelectorFunc(func(ctx context.Context, err error) (bool, error) {
+		<-ctx.Done()
+		return false, nil
+	})
+
+	assert.True(t, OrC(blocking, yes).In(ErrCause))
+	assert.False(t, AndC(no, blocking).In(ErrCause))
+
+	assert.True(t, AndC().In(ErrCause))
+	assert.False(t, OrC().In(ErrCause))
+	assert.True(t, AndC(yes, yes).In(ErrCause))
+	assert.False(t, OrC(no, no).In(ErrCause))
+}
+
+func BenchmarkOrCSlowIO(b *testing.B) {
+	sel := OrC(Grep(""), slow(10*time.Millisecond, badStuff), slow(20*time.Millisecond, okayStuff))
+	for i := 0; i < b.N; i++ {
+		_ = sel.In(ErrSomeErr)
+	}
+}
+
+func TestConcurrencyBound(t *testing.T) {
+	var running, peak int32
+	track := func(ok bool) Selector {
+		return SelectorFunc(func(err error) (bool, error) {
+			n := atomic.AddInt32(&running, 1)
+			for {
+				p := atomic.LoadInt32(&peak)
+				if n <= p || atomic.CompareAndSwapInt32(&peak, p, n) {
+					break
+				}
+			}
+			time.Sleep(time.Millisecond)
+			atomic.AddInt32(&running, -1)
+			return ok, err
+		})
+	}
+
+	ss := make([]Selector, 16)
+	for i := range ss {
+		ss[i] = track(true)
+	}
+	assert.True(t, AndCN(3, ss...).In(ErrCause))
+	assert.True(t, atomic.LoadInt32(&peak) <= 3)
+
+	atomic.StoreInt32(&peak, 0)
+	for i := range ss {
+		ss[i] = track(false)
+	}
+	assert.False(t, OrCN(2, ss...).In(ErrCause))
+	assert.True(t, atomic.LoadInt32(&peak) <= 2)
+
+	assert.True(t, OrCN(0, track(false), track(true)).In(ErrCause))
+	assert.Equal(t, "orc(2, grep(\"x\"))", Describe(OrCN(2, Grep("x"))))
+}
+
+func TestEvery(t *testing.T) {
+	var calls int
+	sel := Every(3, func(error) { calls++ }, okayStuff)
+
+	for i := 0; i < 10; i++ {
+		assert.True(t, sel.In(ErrSomeErr))
+		assert.False(t, sel.In(ErrCause))
+	}
+	assert.Equal(t, 3, calls)
+
+	never := Every(0, func(error) { calls++ }, okayStuff)
+	assert.True(t, never.In(ErrSomeErr))
+	assert.Equal(t, 3, calls)
+}
+
+func TestOnceEvery(t *testing.T) {
+	var calls int
+	sel := OnceEvery(20*time.Millisecond, func(error) { calls++ }, okayStuff)
+
+	assert.True(t, sel.In(ErrSomeErr))
+	assert.True(t, sel.In(ErrSomeErr))
+	assert.False(t, sel.In(ErrCause))
+	assert.Equal(t, 1, calls)
+
+	time.Sleep(30 * time.Millisecond)
+	assert.True(t, sel.In(ErrSomeErr))
+	assert.True(t, sel.In(ErrSomeErr))
+	assert.Equal(t, 2, calls)
+
+	sel.Reset()
+	assert.True(t, sel.In(ErrSomeErr))
+	assert.Equal(t, 3, calls)
+
+	forever := OnceEvery(0, func(error) { calls++ }, okayStuff)
+	forever.In(ErrSomeErr)
+	forever.In(ErrSomeErr)
+	assert.Equal(t, 4, calls)
+	forever.Reset()
+	forever.In(ErrSomeErr)
+	assert.Equal(t, 5, calls)
+}
+
+func TestCallSampled(t *testing.T) {
+	var calls int
+	count := func(error) { calls++ }
+
+	for i := 0; i < 1000; i++ {
+		assert.True(t, CallSampled(0, count, okayStuff).In(ErrSomeErr))
+	}
+	assert.Equal(t, 0, calls)
+
+	for i := 0; i < 1000; i++ {
+		CallSampled(1, count, okayStuff).In(ErrSomeErr)
+		CallSampled(1, count, okayStuff).In(ErrCause)
+	}
+	assert.Equal(t, 1000, calls)
+
+	calls = 0
+	half := CallSampled(0.5, count, okayStuff)
+	for i := 0; i < 10000; i++ {
+		half.In(ErrSomeErr)
+	}
+	assert.InDelta(t, 5000, calls, 500)
+}
+
+func TestMatcher(t *testing.T) {
+	a, b := Named("a"), Named("b")
+	err := a.Lift(b.New("x"))
+
+	for _, sel := range []Selector{a, And(a, b), Or(Not(a), b), Not(And(a, Not(b))), Bind(a, b)} {
+		m, ok := sel.(Matcher)
+		assert.True(t, ok, Describe(sel))
+		ok, _ = sel.Traverse(err)
+		assert.Equal(t, ok, m.MatchBool(err), Describe(sel))
+		assert.Equal(t, ok, sel.In(err), Describe(sel))
+	}
+	assert.False(t, Not(a).(Matcher).MatchBool(err))
+}
+
+func BenchmarkOrIn(b *testing.B) {
+	sel := Or(goodStuff, Not(okayStuff), And(badStuff, okayStuff))
+	for i := 0; i < b.N; i++ {
+		_ = sel.In(ErrSomeErr)
+	}
+}
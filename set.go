@@ -0,0 +1,162 @@
+package errsel
+
+// classSet is a set of classes that can test membership of a class in
+// constant time, with the same semantics as matching each of them.
+type classSet struct {
+	ptrs  map[*class]bool
+	ids   map[uint32]bool
+	names map[uint32]bool // names and aliases of named classes
+	plain map[uint32]bool // names and aliases of those without an identity
+	loose []*class        // classes that aren't interned; see class.loose
+}
+
+func newClassSet(cs []*class) *classSet {
+	s := &classSet{
+		ptrs:  make(map[*class]bool, len(cs)),
+		ids:   make(map[uint32]bool),
+		names: make(map[uint32]bool, len(cs)),
+		plain: make(map[uint32]bool, len(cs)),
+	}
+	for _, cls := range cs {
+		s.add(cls)
+		if cls.dep != nil && cls.dep.replacement != nil {
+			s.add(cls.dep.replacement)
+		}
+	}
+	return s
+}
+
+func (s *classSet) add(cls *class) {
+	s.ptrs[cls] = true
+	if cls.loose {
+		s.loose = append(s.loose, cls)
+		return
+	}
+	if cls.idSym != 0 {
+		s.ids[cls.idSym] = true
+	}
+	if cls.nameSym != 0 {
+		s.names[cls.nameSym] = true
+		for _, a := range cls.aliasSym {
+			s.names[a] = true
+		}
+		if cls.idSym == 0 {
+			s.plain[cls.nameSym] = true
+			for _, a := range cls.aliasSym {
+				s.plain[a] = true
+			}
+		}
+	}
+}
+
+// in reports whether err is annotated with a class in the set, or a
+// descendant of one.
+func (s *classSet) in(err error) bool {
+	c, ok := err.(*classErr)
+	if !ok {
+		return false
+	}
+	for b := range c.classes {
+		for cls := b; cls != nil; cls = cls.parent {
+			if s.has(cls) || cls.dep != nil && cls.dep.replacement != nil && s.has(cls.dep.replacement) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (s *classSet) has(cls *class) bool {
+	if s.ptrs[cls] {
+		return true
+	}
+	for _, l := range s.loose {
+		if l.same(cls) {
+			return true
+		}
+	}
+
+	nameSym, aliasSym, idSym := cls.symbols()
+	if idSym != 0 && s.ids[idSym] {
+		return true
+	}
+	if nameSym == 0 && len(aliasSym) == 0 {
+		return false
+	}
+
+	// classes with an identity match others with one only by identity
+	names := s.names
+	if cls.id != "" {
+		names = s.plain
+	}
+	if names[nameSym] {
+		return true
+	}
+	for _, a := range aliasSym {
+		if names[a] {
+			return true
+		}
+	}
+	return false
+}
+
+// partition splits classes into those constructed by this package, and any
+// others.
+func partition(classes []Class) (native []*class, others []Selector) {
+	for _, c := range classes {
+		if cs := constituents(c); len(cs) == 1 {
+			native = append(native, cs[0])
+		} else {
+			others = append(others, c)
+		}
+	}
+	return native, others
+}
+
+func asSelectors(classes []Class) []Selector {
+	ss := make([]Selector, len(classes))
+	for i, c := range classes {
+		ss[i] = c
+	}
+	return ss
+}
+
+// AnyOf returns a selector that will match if any of the provided classes
+// annotates an error's context chain, returning the first annotated error
+// to match. It is equivalent to Or over the classes, but decides membership
+// for classes constructed by this package in a single traversal of the
+// chain, with a constant time lookup at each class annotation.
+//
+//    var retryable = errsel.AnyOf(timeout, unavailable, conflict, deadlock)
+func AnyOf(classes ...Class) Selector {
+	native, others := partition(classes)
+
+	ss := append([]Selector{Classes(newClassSet(native).in)}, others...)
+	return compose("anyof", SelectorFunc(func(err error) (bool, error) {
+		for _, s := range ss {
+			if ok, er := s.Traverse(err); ok {
+				return true, er
+			}
+		}
+		return false, nil
+	}), asSelectors(classes)...)
+}
+
+// AllOf returns a selector that will match if every one of the provided
+// classes annotates an error's context chain. It is equivalent to And over
+// the classes, but decides membership for up to 64 classes constructed by
+// this package in a single traversal of the chain.
+func AllOf(classes ...Class) Selector {
+	native, others := partition(classes)
+
+	var sel Selector
+	switch {
+	case len(native) > 64 || len(native) == 0:
+		sel = And(asSelectors(classes)...)
+	case len(others) > 0:
+		sel = And(append([]Selector{fuse(native)}, others...)...)
+	default:
+		sel = fuse(native)
+	}
+	return compose("allof", sel, asSelectors(classes)...)
+}
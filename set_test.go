@@ -0,0 +1,53 @@
+package errsel
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAnyOfAllOf(t *testing.T) {
+	timeout, conflict := Named("timeout", Alias("deadline")), Named("conflict")
+	storage := Named("storage")
+	anon, bound := Anonymous(), Bind(Named("x"), Named("y"))
+	shadow := NamedShadow("shadow")
+
+	any := AnyOf(timeout, conflict, anon, bound)
+	all := AllOf(timeout, storage, bound)
+
+	cases := []struct {
+		name     string
+		err      error
+		any, all bool
+	}{
+		{"none", errors.New("x"), false, false},
+		{"named", errors.Wrap(conflict.New("stale"), "wrap"), true, false},
+		{"alias", Named("deadline").New("late"), true, false},
+		{"child", timeout.Child("read").New("late"), true, false},
+		{"anonymous", anon.New("x"), true, false},
+		{"identity by name", Named("conflict").New("x"), true, false},
+		{"bound", bound.New("x"), true, false},
+		{"all", timeout.Lift(storage.Lift(bound.New("x"))), true, true},
+		{"shadowed", shadow.Lift(timeout.Lift(storage.Lift(bound.New("x")))), false, false},
+		{"multi", fmt.Errorf("%w, %w", timeout.New("a"), Bind(storage, bound).New("b")), true, true},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			assert.Equal(t, c.any, any.In(c.err))
+			assert.Equal(t, c.any, Or(timeout, conflict, anon, bound).In(c.err))
+			assert.Equal(t, c.all, all.In(c.err))
+			assert.Equal(t, c.all, And(timeout, storage, bound).In(c.err))
+		})
+	}
+
+	inner := conflict.New("stale")
+	ok, er := any.Traverse(errors.Wrap(inner, "wrap"))
+	assert.True(t, ok)
+	assert.Equal(t, inner, er)
+
+	assert.Equal(t, "anyof(timeout, conflict)", Describe(AnyOf(timeout, conflict)))
+	assert.Equal(t, "allof(timeout, conflict)", Describe(AllOf(timeout, conflict)))
+}
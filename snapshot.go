@@ -0,0 +1,250 @@
+package errsel
+
+import (
+	"fmt"
+	"runtime"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// ChainSnapshot is a serializable representation of an error's context
+// chain, suitable for structured logging.
+type ChainSnapshot struct {
+	// Error is the concatenated message of the whole chain.
+	Error string `json:"error"`
+
+	// Frames holds every intermediate error in the chain, in the order
+	// that Causes would visit them.
+	Frames []FrameSnapshot `json:"frames"`
+}
+
+// FrameSnapshot is a serializable representation of a single intermediate
+// error in a context chain.
+type FrameSnapshot struct {
+	// Depth is the number of errors above this one on its branch.
+	Depth uint `json:"depth"`
+
+	// Type is the go type of the error.
+	Type string `json:"type"`
+
+	// Message is the part of the error's message that it contributes
+	// itself, excluding the message of the error beneath it.
+	Message string `json:"message,omitempty"`
+
+	// Class is the name of the class this error was lifted into, or
+	// "anonymous" for anonymous classes. It is empty if the error isn't a
+	// class annotation.
+	Class string `json:"class,omitempty"`
+
+	// Bound holds the names of any further classes this error was lifted
+	// into, if it was lifted into a bound class.
+	Bound []string `json:"bound,omitempty"`
+
+	// Identity is the stable identity of the class this error was lifted
+	// into, if it has one; see Identity.
+	Identity string `json:"identity,omitempty"`
+
+	// BoundIdentities holds the identities of the classes in Bound, in the
+	// same order, if any of them has one. Those without one are empty.
+	BoundIdentities []string `json:"bound_identities,omitempty"`
+
+	// Shadow reports whether this error is a shadowing class annotation.
+	Shadow bool `json:"shadow,omitempty"`
+
+	// Shadowed reports whether this error is hidden beneath a shadowing
+	// class annotation.
+	Shadowed bool `json:"shadowed,omitempty"`
+
+	// Code is the code of the classes this error was lifted into, if they
+	// have one; see Code.
+	Code *int `json:"code,omitempty"`
+
+	// Fields holds the structured fields this error attaches, if any; see
+	// Lifter.WithFields.
+	Fields Fields `json:"fields,omitempty"`
+
+	// Stack is the stack trace attached to this error, if any.
+	Stack []StackFrame `json:"stack,omitempty"`
+}
+
+// StackFrame is a single frame of a stack trace.
+type StackFrame struct {
+	Function string `json:"function"`
+	File     string `json:"file"`
+	Line     int    `json:"line"`
+}
+
+// String formats the frame as "function file:line".
+func (f StackFrame) String() string {
+	return fmt.Sprintf("%s %s:%d", f.Function, f.File, f.Line)
+}
+
+// StackOf returns the innermost stack trace attached to err's context
+// chain, which is usually the one nearest to where the error originated, or
+// nil if there is none.
+//
+// Any provided traverse options will scope to causes.
+func StackOf(err error, opts ...TraverseOption) []StackFrame {
+	var st errors.StackTrace
+	EachCause(err, func(e error) bool {
+		if t, ok := e.(stackTracer); ok {
+			st = t.StackTrace()
+		}
+		return true
+	}, opts...)
+
+	var frames []StackFrame
+	for _, pc := range st {
+		frames = append(frames, stackFrame(uintptr(pc)))
+	}
+	return frames
+}
+
+type stackTracer interface {
+	StackTrace() errors.StackTrace
+}
+
+// Snapshot captures err's context chain as a ChainSnapshot, including any
+// errors hidden beneath a shadowing class. The messages of errors beneath
+// a class that scrubs messages are scrubbed as well; see Scrub. Those
+// beneath a sensitive class are redacted, as the class's own message is;
+// see Sensitive.
+//
+// Any provided traverse options will scope to causes.
+func Snapshot(err error, opts ...TraverseOption) ChainSnapshot {
+	if err == nil {
+		return ChainSnapshot{}
+	}
+
+	snap := ChainSnapshot{Error: err.Error()}
+	cfg := applyTraverseOpts(opts...)
+
+	var (
+		scrubs   scrubbing
+		secrets  redaction
+		redacted []bool // by frame, whether it is beneath a sensitive class
+	)
+	Walk(err, func(e error, info FrameInfo) bool {
+		redacted = append(redacted, secrets.visit(e, info.Depth))
+		f := FrameSnapshot{
+			Depth:    info.Depth,
+			Type:     fmt.Sprintf("%T", e),
+			Message:  scrubAll(ownMessage(e, cfg), scrubs.visit(e, info.Depth)),
+			Shadow:   info.Shadow,
+			Shadowed: info.Shadowed,
+		}
+
+		switch c := e.(type) {
+		case *classErr:
+			f.Class, f.Identity, f.Code = snapshotName(c.cls), c.cls.id, c.code()
+			for _, cls := range c.also {
+				f.Bound = append(f.Bound, snapshotName(cls))
+				f.BoundIdentities = append(f.BoundIdentities, cls.id)
+			}
+			if !hasIdentity(c.also) {
+				f.BoundIdentities = nil
+			}
+		case *fieldsErr:
+			f.Fields = make(Fields, len(c.fields))
+			for k, v := range c.fields {
+				f.Fields[k] = v
+			}
+		}
+
+		if st, ok := e.(stackTracer); ok {
+			for _, pc := range st.StackTrace() {
+				f.Stack = append(f.Stack, stackFrame(uintptr(pc)))
+			}
+		}
+
+		snap.Frames = append(snap.Frames, f)
+		return true
+	}, opts...)
+
+	// beneath a sensitive class, only the innermost errors say anything
+	for i := range snap.Frames {
+		if !redacted[i] {
+			continue
+		}
+		f := &snap.Frames[i]
+		if i+1 == len(snap.Frames) || snap.Frames[i+1].Depth <= f.Depth {
+			f.Message = Redacted
+		} else {
+			f.Message = ""
+		}
+	}
+	return snap
+}
+
+// redaction tracks, during a walk, which frames are beneath a sensitive
+// class.
+type redaction struct {
+	beneath []bool // by depth, whether the frames beneath it are redacted
+}
+
+// visit reports whether e, visited at depth, is beneath a sensitive class.
+func (r *redaction) visit(e error, depth uint) bool {
+	for uint(len(r.beneath)) < depth {
+		r.beneath = append(r.beneath, false)
+	}
+	r.beneath = r.beneath[:depth]
+
+	above := depth > 0 && r.beneath[depth-1]
+	c, ok := e.(*classErr)
+	r.beneath = append(r.beneath, above || ok && c.cls.sensitive())
+	return above
+}
+
+// hasIdentity reports whether any of classes has a stable identity.
+func hasIdentity(classes []*class) bool {
+	for _, cls := range classes {
+		if cls.id != "" {
+			return true
+		}
+	}
+	return false
+}
+
+func snapshotName(cls *class) string {
+	if cls.named {
+		return cls.name
+	}
+	return "anonymous"
+}
+
+// ownMessage returns the part of err's message that isn't contributed by
+// the error beneath it.
+func ownMessage(err error, cfg *traverseConfig) string {
+	switch err.(type) {
+	case *classErr, *scrubErr:
+		// their messages are those beneath, decorated or scrubbed
+		return ""
+	}
+
+	msg := err.Error()
+	next, _ := cfg.next(err)
+	if next == nil {
+		return msg
+	}
+
+	if inner := next.Error(); strings.HasSuffix(msg, inner) {
+		msg = strings.TrimSuffix(msg, inner)
+		msg = strings.TrimSuffix(msg, ": ")
+	}
+	return msg
+}
+
+func stackFrame(pc uintptr) StackFrame {
+	// pkg/errors records return addresses; step back into the call
+	fn := runtime.FuncForPC(pc - 1)
+	if fn == nil {
+		return StackFrame{Function: "unknown", File: "unknown"}
+	}
+	file, line := fn.FileLine(pc - 1)
+	return StackFrame{
+		Function: fn.Name(),
+		File:     file,
+		Line:     line,
+	}
+}
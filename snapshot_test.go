@@ -0,0 +1,57 @@
+package errsel
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSnapshot(t *testing.T) {
+	database, conflict := Named("database"), NamedShadow("conflict")
+	err := errors.WithMessage(conflict.Lift(database.New("btree")), "query")
+
+	snap := Snapshot(err)
+	assert.Equal(t, err.Error(), snap.Error)
+	assert.Len(t, snap.Frames, 4)
+
+	assert.Equal(t, "query", snap.Frames[0].Message)
+	assert.Equal(t, FrameSnapshot{
+		Depth:  1,
+		Type:   "*errsel.classErr",
+		Class:  "conflict",
+		Shadow: true,
+	}, snap.Frames[1])
+	assert.Equal(t, "database", snap.Frames[2].Class)
+	assert.True(t, snap.Frames[2].Shadowed)
+
+	root := snap.Frames[3]
+	assert.Equal(t, "btree", root.Message)
+	assert.NotEmpty(t, root.Stack)
+
+	var found bool
+	for _, f := range root.Stack {
+		if strings.HasSuffix(f.Function, "TestSnapshot") {
+			found = strings.HasSuffix(f.File, "snapshot_test.go") && f.Line > 0
+		}
+	}
+	assert.True(t, found)
+
+	_, err = json.Marshal(snap)
+	assert.NoError(t, err)
+
+	assert.Equal(t, ChainSnapshot{}, Snapshot(nil))
+}
+
+func TestStackOf(t *testing.T) {
+	assert.Nil(t, StackOf(Stdlib.New("x")))
+
+	err := Named("a").Wrap(Named("b").New("x"), "y")
+	st := StackOf(err)
+	assert.NotEmpty(t, st)
+	assert.Contains(t, st[0].Function, "pkgBackend.New")
+	assert.Contains(t, st[2].String(), "errsel.TestStackOf ")
+	assert.Contains(t, st[2].String(), "snapshot_test.go:")
+}
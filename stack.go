@@ -0,0 +1,150 @@
+package errsel
+
+import (
+	"fmt"
+	"runtime"
+
+	"github.com/pkg/errors"
+)
+
+// LazyStacks is a backend that attaches stack traces like Compat, but that
+// records only the program counters of a stack when an error is created,
+// keeping them inline in the error. Frames are resolved to functions, files
+// and lines only when a stack trace is formatted or retrieved, which makes
+// creating and wrapping errors considerably cheaper on hot paths.
+//
+// Its errors implement StackTrace() errors.StackTrace, so they remain
+// compatible with consumers of github.com/pkg/errors, and are compatible
+// with the standard library's errors.Unwrap. At most 32 frames are
+// recorded.
+var LazyStacks Backend = lazyBackend{}
+
+const lazyDepth = 32
+
+type lazyBackend struct{}
+
+func (lazyBackend) New(msg string) error {
+	e := &lazyNew{msg: msg}
+	e.capture()
+	return e
+}
+
+func (lazyBackend) Errorf(format string, args ...interface{}) error {
+	e := &lazyWrap{err: fmt.Errorf(format, args...)}
+	e.capture()
+	return e
+}
+
+func (lazyBackend) WithStack(err error) error {
+	if err == nil {
+		return nil
+	}
+	e := &lazyWrap{err: err}
+	e.capture()
+	return e
+}
+
+func (lazyBackend) WithMessage(err error, msg string) error {
+	return stdBackend{}.WithMessage(err, msg)
+}
+
+func (lazyBackend) Wrap(err error, msg string) error {
+	if err == nil {
+		return nil
+	}
+	e := &lazyWrap{err: stdBackend{}.WithMessage(err, msg)}
+	e.capture()
+	return e
+}
+
+func (lazyBackend) Wrapf(err error, format string, args ...interface{}) error {
+	if err == nil {
+		return nil
+	}
+	e := &lazyWrap{err: stdBackend{}.WithMessage(err, fmt.Sprintf(format, args...))}
+	e.capture()
+	return e
+}
+
+func (lazyBackend) Unwrap(err error) error {
+	if c, ok := err.(causer); ok {
+		return c.Cause()
+	}
+	return nil
+}
+
+// lazyStack holds the program counters of the stack an error was created
+// on.
+type lazyStack struct {
+	pcs [lazyDepth]uintptr
+	n   int
+}
+
+// capture records the stack of the caller of the backend method calling
+// capture.
+func (st *lazyStack) capture() {
+	st.n = runtime.Callers(3, st.pcs[:])
+}
+
+func (st *lazyStack) StackTrace() errors.StackTrace {
+	frames := make(errors.StackTrace, st.n)
+	for i, pc := range st.pcs[:st.n] {
+		frames[i] = errors.Frame(pc)
+	}
+	return frames
+}
+
+// lazyNew is a new error with a stack.
+type lazyNew struct {
+	msg string
+	lazyStack
+}
+
+func (e *lazyNew) Error() string {
+	return e.msg
+}
+
+func (e *lazyNew) Format(s fmt.State, verb rune) {
+	formatLazy(s, verb, e.msg, e.StackTrace)
+}
+
+// lazyWrap annotates err with a stack.
+type lazyWrap struct {
+	err error
+	lazyStack
+}
+
+func (e *lazyWrap) Error() string {
+	return e.err.Error()
+}
+
+func (e *lazyWrap) Cause() error {
+	return e.err
+}
+
+func (e *lazyWrap) Unwrap() error {
+	return e.err
+}
+
+func (e *lazyWrap) Format(s fmt.State, verb rune) {
+	formatLazy(s, verb, e.err, e.StackTrace)
+}
+
+// formatLazy formats an error with a lazily resolved stack, as the errors of
+// github.com/pkg/errors are formatted: %+v includes the stack, while other
+// verbs print only the message.
+func formatLazy(s fmt.State, verb rune, msg interface{}, stack func() errors.StackTrace) {
+	switch verb {
+	case 'v':
+		if s.Flag('+') {
+			fmt.Fprintf(s, "%+v", msg)
+			stack().Format(s, verb)
+			return
+		}
+		fallthrough
+	case 's':
+		fmt.Fprintf(s, "%s", msg)
+	case 'q':
+		fmt.Fprintf(s, "%q", msg)
+	}
+}
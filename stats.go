@@ -0,0 +1,135 @@
+package errsel
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// ClassStats describes how a registered class has been used since it was
+// registered.
+type ClassStats struct {
+	Name      string    // the name the class is registered under
+	Lifts     uint64    // number of errors lifted into the class
+	Matches   uint64    // number of errors the class matched as a selector
+	LastLift  time.Time // when an error was last lifted, or the zero time
+	LastMatch time.Time // when an error was last matched, or the zero time
+}
+
+// classStats counts the use of a registered class.
+type classStats struct {
+	lifts, matches      atomic.Uint64
+	lastLift, lastMatch atomic.Int64 // in unix nanoseconds
+}
+
+func (s *classStats) snapshot(name string) ClassStats {
+	st := ClassStats{
+		Name:    name,
+		Lifts:   s.lifts.Load(),
+		Matches: s.matches.Load(),
+	}
+	if ns := s.lastLift.Load(); ns != 0 {
+		st.LastLift = time.Unix(0, ns)
+	}
+	if ns := s.lastMatch.Load(); ns != 0 {
+		st.LastMatch = time.Unix(0, ns)
+	}
+	return st
+}
+
+// countLift records a lift into e, if its registry collects stats or expvar
+// counters are published.
+func countLift(e *class) {
+	if s := e.stats.Load(); s != nil {
+		s.lifts.Add(1)
+		s.lastLift.Store(time.Now().UnixNano())
+	}
+	if expvarOn.Load() {
+		expvarLifts.Add(e.String(), 1)
+	}
+}
+
+// countMatch records a match by e, if its registry collects stats.
+func countMatch(e *class) {
+	if s := e.stats.Load(); s != nil {
+		s.matches.Add(1)
+		s.lastMatch.Store(time.Now().UnixNano())
+	}
+}
+
+func countMatches(cs []*class) {
+	for _, e := range cs {
+		countMatch(e)
+	}
+}
+
+// CollectStats enables usage statistics for the classes in the registry,
+// both those already registered and those registered later; see Stats.
+// Usage isn't counted by default, as counting costs a clock read and
+// atomic updates on every lift into and match by a registered class.
+func (r *Registry) CollectStats() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.stats = true
+	for _, cls := range r.classes {
+		collectStats(cls)
+	}
+}
+
+// collectStats starts counting the use of cls, if it was constructed by this
+// package.
+func collectStats(cls Class) {
+	if e := nativeOf(cls); e != nil {
+		e.stats.CompareAndSwap(nil, new(classStats))
+	}
+}
+
+// nativeOf returns the class constructed by this package that cls is, or
+// nil if it isn't one.
+func nativeOf(cls Class) *class {
+	for {
+		switch c := cls.(type) {
+		case *errClass:
+			return c.cls
+		case sealed:
+			cls = c.Class
+		case *Boundary:
+			cls = c.Class
+		case interface{ native() *class }:
+			return c.native()
+		default:
+			return nil
+		}
+	}
+}
+
+// Stats returns usage statistics for every registered class, sorted by
+// name, suitable for exposing on a debug endpoint once CollectStats has
+// been called:
+//
+//    registry.CollectStats()
+//
+//    http.HandleFunc("/debug/errors", func(w http.ResponseWriter, r *http.Request) {
+//        json.NewEncoder(w).Encode(registry.Stats())
+//    })
+//
+// Lifts are counted however an error is lifted into a class. Matches are
+// counted when the class is evaluated as a selector, alone or through
+// AllOf; matches decided by AnyOf or other selectors built from the
+// class's name aren't counted. Usage is only counted for classes
+// constructed by this package, and from the time they're registered or
+// CollectStats is called, whichever is later.
+func (r *Registry) Stats() []ClassStats {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var stats []ClassStats
+	for _, name := range r.names() {
+		if e := nativeOf(r.classes[name]); e != nil && e.stats.Load() != nil {
+			stats = append(stats, e.stats.Load().snapshot(name))
+			continue
+		}
+		stats = append(stats, ClassStats{Name: name})
+	}
+	return stats
+}
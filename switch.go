@@ -0,0 +1,50 @@
+package errsel
+
+// Switcher routes an error to the handler of the first matching case.
+type Switcher struct {
+	err     error
+	handled bool
+}
+
+// Switch begins routing err through a series of cases. Cases are evaluated
+// in order, and only the handler of the first matching case is called;
+// once a case has matched, later cases are not evaluated at all.
+//
+//    errsel.Switch(err).
+//        Case(conflict, handleConflict).
+//        Case(notFound, handle404).
+//        Default(handle500)
+//
+// If err is nil, no handler will be called.
+func Switch(err error) *Switcher {
+	return &Switcher{
+		err:     err,
+		handled: err == nil,
+	}
+}
+
+// Case calls f with the intermediate error matched by s, if s matches and
+// no earlier case has.
+func (s *Switcher) Case(sel Selector, f func(error)) *Switcher {
+	if s.handled {
+		return s
+	}
+	if ok, er := sel.Traverse(s.err); ok {
+		s.handled = true
+		f(er)
+	}
+	return s
+}
+
+// Default calls f with the error being routed, if no case has matched.
+func (s *Switcher) Default(f func(error)) {
+	if !s.handled {
+		s.handled = true
+		f(s.err)
+	}
+}
+
+// Handled reports whether a handler has been called.
+func (s *Switcher) Handled() bool {
+	return s.handled && s.err != nil
+}
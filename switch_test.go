@@ -0,0 +1,43 @@
+package errsel
+
+import (
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSwitch(t *testing.T) {
+	conflict, notFound := Named("conflict"), Named("not_found")
+
+	route := func(err error) (code int, matched error) {
+		Switch(err).
+			Case(conflict, func(er error) { code, matched = 409, er }).
+			Case(notFound, func(er error) { code, matched = 404, er }).
+			Case(Grep("not_found"), func(er error) { code, matched = 400, er }).
+			Default(func(er error) { code, matched = 500, er })
+		return code, matched
+	}
+
+	inner := notFound.New("no such row")
+	err := errors.Wrap(inner, "lookup")
+
+	code, matched := route(err)
+	assert.Equal(t, 404, code)
+	assert.Equal(t, inner, matched)
+
+	code, _ = route(conflict.Lift(err))
+	assert.Equal(t, 409, code)
+
+	plain := errors.New("oops")
+	code, matched = route(plain)
+	assert.Equal(t, 500, code)
+	assert.Equal(t, plain, matched)
+
+	code, _ = route(nil)
+	assert.Equal(t, 0, code)
+
+	assert.True(t, Switch(err).Case(notFound, func(error) {}).Handled())
+	assert.False(t, Switch(err).Case(conflict, func(error) {}).Handled())
+	assert.False(t, Switch(nil).Handled())
+}
@@ -0,0 +1,106 @@
+package errsel
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Template sets a message template for errors constructed with the class's
+// NewT method. Placeholders of the form {name} are replaced by the value of
+// the corresponding arg; placeholders without a matching arg are left as is.
+// Classes inherit the template of their parents.
+//
+//    var quota = Named("quota", Template("quota exceeded for {user}: {limit}"))
+//
+// Classes without a template render their default message instead.
+func Template(tmpl string) ClassOption {
+	return ClassOption(func(e *class) {
+		e.tmpl = tmpl
+	})
+}
+
+// template returns the template of errors constructed with NewT.
+func (e *class) template() string {
+	for cls := e; cls != nil; cls = cls.parent {
+		if cls.tmpl != "" {
+			return cls.tmpl
+		}
+	}
+	return e.message()
+}
+
+// templateErr is an error whose message was rendered from a template. It
+// keeps the template and its args, so they can be extracted or rendered
+// again later.
+type templateErr struct {
+	err  error
+	tmpl string
+	args Fields
+}
+
+func newTemplateErr(tmpl string, args Fields) error {
+	cp := make(Fields, len(args))
+	for k, v := range args {
+		cp[k] = v
+	}
+	return &templateErr{
+		err:  CurrentBackend().New(render(tmpl, cp)),
+		tmpl: tmpl,
+		args: cp,
+	}
+}
+
+func (t *templateErr) Error() string {
+	return t.err.Error()
+}
+
+func (t *templateErr) Cause() error {
+	return t.err
+}
+
+func (t *templateErr) Unwrap() error {
+	return t.err
+}
+
+// render replaces every {name} placeholder in tmpl with the corresponding
+// arg.
+func render(tmpl string, args Fields) string {
+	var b strings.Builder
+	for {
+		i := strings.IndexByte(tmpl, '{')
+		if i < 0 {
+			break
+		}
+		j := strings.IndexByte(tmpl[i:], '}')
+		if j < 0 {
+			break
+		}
+		j += i
+
+		b.WriteString(tmpl[:i])
+		if v, ok := args[tmpl[i+1:j]]; ok {
+			fmt.Fprint(&b, v)
+		} else {
+			b.WriteString(tmpl[i : j+1])
+		}
+		tmpl = tmpl[j+1:]
+	}
+	b.WriteString(tmpl)
+	return b.String()
+}
+
+// ArgsOf returns the args of the outermost error in err's context chain
+// that was constructed with NewT. Like ValueOf, args beneath a shadowing
+// class are not visible.
+//
+// Any provided traverse options will scope to causes.
+func ArgsOf(err error, opts ...TraverseOption) (Fields, bool) {
+	var args Fields
+	Walk(err, func(e error, info FrameInfo) bool {
+		if t, ok := e.(*templateErr); ok && !info.Shadowed {
+			args = t.args
+		}
+		return args == nil
+	}, opts...)
+	return args, args != nil
+}
@@ -0,0 +1,30 @@
+package errsel
+
+import (
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTemplate(t *testing.T) {
+	quota := Named("quota", Template("quota exceeded for {user}: {limit} {unit}"))
+	storage := quota.Child("storage")
+
+	err := storage.NewT(Fields{"user": "alice", "limit": 10})
+	assert.Equal(t, "storage{ quota exceeded for alice: 10 {unit} }", err.Error())
+	assert.True(t, quota.In(err))
+
+	args, ok := ArgsOf(errors.Wrap(err, "upload"))
+	assert.True(t, ok)
+	assert.Equal(t, Fields{"user": "alice", "limit": 10}, args)
+
+	_, ok = ArgsOf(NamedShadow("api").Lift(err))
+	assert.False(t, ok)
+	_, ok = ArgsOf(quota.New("plain"))
+	assert.False(t, ok)
+
+	assert.Equal(t, "plain{ busy }", Named("plain", DefaultMessage("busy")).NewT(nil).Error())
+	assert.Equal(t, "a {b c}", render("{a} {b c}", Fields{"a": "a"}))
+	assert.Equal(t, "unterminated {a", render("unterminated {a", Fields{"a": 1}))
+}
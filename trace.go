@@ -0,0 +1,165 @@
+package errsel
+
+import (
+	"fmt"
+	"strings"
+	"sync/atomic"
+)
+
+// TraceKind identifies what happened at a step of a traced traversal.
+type TraceKind uint8
+
+const (
+	// TraceVisit reports that an error was visited, and whether the
+	// selector matched it.
+	TraceVisit TraceKind = iota
+
+	// TraceLens reports that an error was skipped by a lens; see Lens,
+	// ClassLens and LensFromCause.
+	TraceLens
+
+	// TraceAnchor reports that an error was skipped because it is above
+	// the anchor set by LensTo or Between.
+	TraceAnchor
+
+	// TraceUntil reports that traversal of a branch stopped at the inner
+	// boundary set by Between.
+	TraceUntil
+
+	// TraceDepth reports that traversal of a branch stopped at the limit
+	// set by Depth or ClassDepth.
+	TraceDepth
+
+	// TraceShadow reports that a visited error is a shadowing class
+	// annotation, hiding the classes beneath it.
+	TraceShadow
+
+	// TraceCycle reports that traversal of a branch stopped because it
+	// loops back on itself.
+	TraceCycle
+
+	// TraceLimit reports that traversal stopped at the limit set by
+	// SetMaxChainLength.
+	TraceLimit
+)
+
+var traceKinds = [...]string{"visit", "lens", "anchor", "until", "depth", "shadow", "cycle", "limit"}
+
+func (k TraceKind) String() string {
+	if int(k) < len(traceKinds) {
+		return traceKinds[k]
+	}
+	return fmt.Sprintf("TraceKind(%d)", uint8(k))
+}
+
+// TraceStep describes a single step of a traced traversal.
+type TraceStep struct {
+	// Kind is what happened at this step.
+	Kind TraceKind
+
+	// Err is the error at the traversal's cursor.
+	Err error
+
+	// Depth is the number of errors above Err on its branch of the chain,
+	// not counting any skipped by a lens.
+	Depth uint
+
+	// Class is the class Err was lifted into, or nil if it isn't a class
+	// annotation.
+	Class Class
+
+	// Shadowed reports whether Err is hidden beneath a shadowing class
+	// annotation.
+	Shadowed bool
+
+	// Match reports whether the selector matched Err. It is only set for
+	// TraceVisit steps.
+	Match bool
+}
+
+// String renders the step on a single line, such as
+//
+//    visit depth=1 class=database shadowed match: connection refused
+func (s TraceStep) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s depth=%d", s.Kind, s.Depth)
+	if s.Class != nil {
+		fmt.Fprintf(&b, " class=%s", Describe(s.Class))
+	}
+	if s.Shadowed {
+		b.WriteString(" shadowed")
+	}
+	if s.Match {
+		b.WriteString(" match")
+	}
+	if s.Err != nil {
+		fmt.Fprintf(&b, ": %v", s.Err)
+	}
+	return b.String()
+}
+
+// Trace reports every step of traversal to f, such as the errors visited,
+// the classes seen, shadows encountered, and the decisions of lenses and
+// depth limits. It is meant for diagnosing why a selector doesn't match.
+//
+//    sel := errsel.Classes(timeout.In, errsel.ClassLens(1), errsel.Trace(func(s errsel.TraceStep) {
+//        log.Print(s)
+//    }))
+//
+// Trace applies to the selectors and helpers that accept traverse options.
+// To trace the traversals of every selector, including classes themselves,
+// use SetTracer.
+func Trace(f func(TraceStep)) TraverseOption {
+	return TraverseOption(func(c *traverseConfig) {
+		c.trace = f
+	})
+}
+
+var globalTracer atomic.Pointer[func(TraceStep)]
+
+// SetTracer reports every step of every traversal to f, unless a
+// traversal has its own tracer set by Trace. Providing nil disables global
+// tracing. It is safe for concurrent use.
+//
+// Global tracing is expensive, and classes decide membership by traversal
+// rather than by the cached index of an error while it is enabled. It is
+// meant to be enabled temporarily, while diagnosing a problem.
+func SetTracer(f func(TraceStep)) {
+	if f == nil {
+		globalTracer.Store(nil)
+		return
+	}
+	globalTracer.Store(&f)
+}
+
+// tracing reports whether global tracing is enabled.
+func tracing() bool {
+	return globalTracer.Load() != nil
+}
+
+// tracer returns the function that traversal under c reports its steps to,
+// or nil if it isn't traced.
+func (c *traverseConfig) tracer() func(TraceStep) {
+	if c.trace != nil {
+		return c.trace
+	}
+	if f := globalTracer.Load(); f != nil {
+		return *f
+	}
+	return nil
+}
+
+// traceStep reports a step at f to tr.
+func traceStep(tr func(TraceStep), kind TraceKind, f frame, match bool) {
+	s := TraceStep{
+		Kind:     kind,
+		Err:      f.err,
+		Depth:    f.depth,
+		Shadowed: f.shadowed,
+		Match:    match,
+	}
+	if c, ok := f.err.(*classErr); ok {
+		s.Class = c.cls.self
+	}
+	tr(s)
+}
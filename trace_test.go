@@ -0,0 +1,72 @@
+package errsel
+
+import (
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTrace(t *testing.T) {
+	inner, boundary := Named("trace.inner"), NamedShadow("trace.boundary")
+	err := errors.WithMessage(boundary.Wrap(inner.New("root"), "outer"), "top")
+
+	var steps []TraceStep
+	record := Trace(func(s TraceStep) { steps = append(steps, s) })
+
+	assert.False(t, Classes(inner.In, record).In(err))
+
+	kinds := make([]TraceKind, len(steps))
+	for i, s := range steps {
+		kinds[i] = s.Kind
+	}
+	assert.Equal(t, []TraceKind{TraceVisit, TraceVisit, TraceShadow}, kinds)
+	assert.Equal(t, uint(1), steps[1].Depth)
+	assert.Equal(t, boundary, steps[1].Class)
+	assert.False(t, steps[1].Match)
+	assert.Equal(t, "shadow depth=1 class=trace.boundary#: trace.boundary#{ outer: trace.inner{ root } }", steps[2].String())
+
+	steps = nil
+	assert.False(t, Classes(inner.In, Lens(2), Depth(2), record).In(err))
+	assert.Len(t, steps, 5)
+	assert.Equal(t, TraceLens, steps[0].Kind)
+	assert.Equal(t, TraceLens, steps[1].Kind)
+	assert.Equal(t, TraceVisit, steps[2].Kind)
+	assert.Equal(t, TraceDepth, steps[4].Kind)
+	assert.Equal(t, "depth depth=2 class=trace.inner: trace.inner{ root }", steps[4].String())
+
+	steps = nil
+	assert.True(t, Classes(inner.In, Lens(2), record).In(err))
+	assert.True(t, steps[4].Match)
+	assert.Equal(t, "visit depth=2 class=trace.inner match: trace.inner{ root }", steps[4].String())
+
+	steps = nil
+	assert.False(t, Causes(func(error) bool { return false }, Depth(1), Reverse(), record).In(err))
+	assert.Len(t, steps, 2)
+	assert.Equal(t, TraceDepth, steps[0].Kind)
+	assert.Equal(t, TraceVisit, steps[1].Kind)
+	assert.Equal(t, uint(0), steps[1].Depth)
+
+	assert.Equal(t, "TraceKind(42)", TraceKind(42).String())
+}
+
+func TestSetTracer(t *testing.T) {
+	cls := Named("trace.global")
+	lifted := cls.Wrap(errors.New("x"), "y")
+
+	var matched []TraceStep
+	SetTracer(func(s TraceStep) {
+		if s.Match {
+			matched = append(matched, s)
+		}
+	})
+	assert.True(t, cls.In(lifted))
+	assert.False(t, AllOf(cls, Named("trace.other")).In(lifted))
+	SetTracer(nil)
+
+	assert.Len(t, matched, 1)
+	assert.Equal(t, cls, matched[0].Class)
+
+	assert.True(t, cls.In(lifted))
+	assert.Len(t, matched, 1)
+}
@@ -1,12 +1,29 @@
 package errsel
 
+import (
+	"sync"
+	"sync/atomic"
+)
+
 type traverseConfig struct {
-	lens  uint
-	depth uint
+	lens        uint
+	lensClasses bool
+	fromCause   bool
+	anchor      func(error) bool
+	until       func(error) bool
+	depth       uint
+	classDepth  uint
+	follow      ChainInterface
+	breadth     bool
+	reverse     bool
+	unshadow    bool
+	bypass      []*class
+	trace       func(TraceStep)
+	quiet       bool // don't trace visits, as when collecting frames
 }
 
 func applyTraverseOpts(opts ...TraverseOption) *traverseConfig {
-	cfg := new(traverseConfig)
+	cfg := &traverseConfig{follow: FollowAll}
 	for _, f := range opts {
 		f(cfg)
 	}
@@ -15,20 +32,586 @@ func applyTraverseOpts(opts ...TraverseOption) *traverseConfig {
 
 type TraverseOption func(*traverseConfig)
 
-// Lens sets lensing depth to k elements.
+// ChainInterface is a set of interfaces that traversal will follow to
+// reach the next error in a context chain.
+type ChainInterface uint
+
+const (
+	// FollowCause follows errors implementing Cause() error, as used by
+	// github.com/pkg/errors.
+	FollowCause ChainInterface = 1 << iota
+
+	// FollowUnwrap follows errors implementing Unwrap() error, as used by
+	// fmt.Errorf with the %w verb.
+	FollowUnwrap
+
+	// FollowMulti follows errors implementing Unwrap() []error, as used by
+	// errors.Join and fmt.Errorf with multiple %w verbs. Every wrapped error
+	// becomes a separate branch of the traversal.
+	FollowMulti
+
+	// FollowAggregate follows error aggregates implementing WrappedErrors()
+	// []error, as used by github.com/hashicorp/go-multierror, or Errors()
+	// []error, as used by go.uber.org/multierr. Every aggregated error
+	// becomes a separate branch of the traversal.
+	FollowAggregate
+
+	// FollowRegistered follows unwrappers added with RegisterUnwrapper.
+	FollowRegistered
+
+	// FollowAll follows every supported chain interface. This is the
+	// default.
+	FollowAll = FollowCause | FollowUnwrap | FollowMulti | FollowAggregate | FollowRegistered
+)
+
+// Follow sets which chain interfaces will be followed during traversal.
+// If an error implements more than one, they take precedence in the order
+// Cause, aggregates, Unwrap, multi-error Unwrap, then registered
+// unwrappers.
+func Follow(ch ChainInterface) TraverseOption {
+	return TraverseOption(func(c *traverseConfig) {
+		c.follow = ch
+	})
+}
+
+// Lens sets lensing depth to k elements. Traversal will begin k raw
+// frames into the context chain, regardless of whether those frames
+// were annotated with a class.
+//
+// If the chain is shorter than k, nothing will be matched.
 func Lens(k uint) TraverseOption {
 	return TraverseOption(func(c *traverseConfig) {
 		c.lens = k
+		c.lensClasses = false
+		c.fromCause = false
+	})
+}
+
+// ClassLens sets lensing depth to k class annotations. Traversal will
+// begin just beneath the k-th class annotated frame in the context chain,
+// skipping any unannotated frames along the way. Skipped classes are not
+// matched, and their shadowing is not respected.
+//
+// If the chain has fewer than k class annotations, nothing will be matched.
+func ClassLens(k uint) TraverseOption {
+	return TraverseOption(func(c *traverseConfig) {
+		c.lens = k
+		c.lensClasses = true
+		c.fromCause = false
+	})
+}
+
+// LensFromCause sets lensing depth to k elements from the root cause.
+// Traversal will begin k raw frames above the root cause, so LensFromCause(0)
+// visits only the root cause itself. This is useful when errors are wrapped
+// an unknown number of times above the frames of interest.
+//
+// When traversing multi-errors, k is measured from the deepest root cause.
+// If the chain is not longer than k, nothing will be matched.
+func LensFromCause(k uint) TraverseOption {
+	return TraverseOption(func(c *traverseConfig) {
+		c.lens = k
+		c.lensClasses = false
+		c.fromCause = true
+	})
+}
+
+// ClassDepth sets maximum traversal depth to d class annotations. Traversal
+// stops upon reaching the d-th class annotated frame beneath the first,
+// regardless of how many unannotated frames are in between; so ClassDepth(2)
+// will visit only the outermost two classes. When traversing multi-errors,
+// depth is measured along each branch.
+//
+// It can be combined with Depth, in which case traversal stops at whichever
+// limit is reached first.
+func ClassDepth(d uint) TraverseOption {
+	return TraverseOption(func(c *traverseConfig) {
+		c.classDepth = d
+	})
+}
+
+// LensTo lenses traversal to the first error matched by sel, such as a
+// class annotation. Traversal will begin just beneath it, skipping it and
+// everything above it along the way. This scopes traversal to errors beneath
+// a boundary, regardless of how many times it has been wrapped.
+//
+//    errsel.Classes(timeout.In, errsel.LensTo(storage))
+//
+// Skipped classes are not matched, and their shadowing is not respected. If
+// sel matches nothing, nothing will be matched.
+func LensTo(sel Selector) TraverseOption {
+	return TraverseOption(func(c *traverseConfig) {
+		c.anchor = atFrame(sel)
+	})
+}
+
+// Between limits traversal to the segment of the context chain between the
+// first error matched by outer and the next error matched by inner beneath
+// it, exclusive of both. This is useful in layered architectures, to test
+// whether a condition arose within a specific layer.
+//
+//    // did a timeout occur in the service layer, above storage?
+//    errsel.Classes(timeout.In, errsel.Between(service, storage))
+//
+// If outer matches nothing, nothing will be matched. If inner matches
+// nothing, traversal continues to the end of the chain.
+func Between(outer, inner Selector) TraverseOption {
+	return TraverseOption(func(c *traverseConfig) {
+		c.anchor = atFrame(outer)
+		c.until = atFrame(inner)
 	})
 }
 
-// Depth sets maximum traversal depth to d elements.
+// atFrame returns a function that reports whether sel matches an error
+// itself, rather than something in its context chain.
+func atFrame(sel Selector) func(error) bool {
+	if c, ok := sel.(*errClass); ok && c.cls != nil {
+		return c.cls.in
+	}
+	return func(err error) bool {
+		ok, e := sel.Traverse(err)
+		return ok && sameErr(e, err)
+	}
+}
+
+// Depth sets maximum traversal depth to d elements. When traversing
+// multi-errors, depth is measured along each branch.
 func Depth(d uint) TraverseOption {
 	return TraverseOption(func(c *traverseConfig) {
 		c.depth = d
 	})
 }
 
+// DepthFirst visits the branches of multi-errors in depth first order,
+// exhausting each branch before moving on to the next. This is the default,
+// and matches the order used by the standard library's errors.Is.
+func DepthFirst() TraverseOption {
+	return TraverseOption(func(c *traverseConfig) {
+		c.breadth = false
+	})
+}
+
+// BreadthFirst visits the branches of multi-errors in breadth first order,
+// visiting every error at a given depth before descending further.
+func BreadthFirst() TraverseOption {
+	return TraverseOption(func(c *traverseConfig) {
+		c.breadth = true
+	})
+}
+
+// Reverse visits the context chain in reverse, from the root cause outward,
+// so that the innermost match wins instead of the outermost. Lensing, depth
+// and shadowing are applied as they would be in the forward direction.
+//
+// Traversal in reverse must first walk the whole chain to collect it, so it
+// allocates, and can't stop early upon finding a match.
+func Reverse() TraverseOption {
+	return TraverseOption(func(c *traverseConfig) {
+		c.reverse = true
+	})
+}
+
+// IgnoreShadow disables class shadowing during traversal, so that classes
+// hidden beneath a shadowing class will be visited. It applies to every
+// selector and extraction helper that respects shadowing, such as MetaOf,
+// ValueOf and FieldsOf.
+//
+// Shadowing exists so that a package can hide its internal classes from
+// the handlers of its clients; IgnoreShadow is meant for observability code,
+// such as logging and diagnostics, that must see through those boundaries.
+// Handlers that make control flow decisions should not use it.
+//
+//    // log every class, including those hidden from handlers
+//    log.Print(errsel.ClassesOf(err, errsel.IgnoreShadow()))
+func IgnoreShadow() TraverseOption {
+	return TraverseOption(func(c *traverseConfig) {
+		c.unshadow = true
+	})
+}
+
+// Bypass is a capability to see through the shadow of a specific class. It
+// can only be minted upon the class's construction, with MintBypass, so that
+// a package can keep it to itself while exporting the class.
+//
+// The zero value bypasses nothing.
+type Bypass struct {
+	cls *class
+}
+
+// MintBypass mints a Bypass for the class being constructed, storing it in
+// b. Keeping b unexported gives a package controlled visibility through a
+// boundary that everyone else must respect.
+//
+//    var internal errsel.Bypass
+//
+//    var Boundary = errsel.NamedShadow("boundary", errsel.MintBypass(&internal))
+//
+//    // only this package can see beneath the boundary
+//    errsel.Classes(diagnose, errsel.Through(internal))
+func MintBypass(b *Bypass) ClassOption {
+	return ClassOption(func(e *class) {
+		b.cls = e
+	})
+}
+
+// Through allows traversal to see through the shadows of the classes that
+// minted the provided bypasses. Shadows of other classes, including any
+// nested beneath a bypassed one, are still respected.
+func Through(bs ...Bypass) TraverseOption {
+	return TraverseOption(func(c *traverseConfig) {
+		for _, b := range bs {
+			if b.cls != nil {
+				c.bypass = append(c.bypass, b.cls)
+			}
+		}
+	})
+}
+
+func (c *traverseConfig) bypassed(cls *class) bool {
+	for _, b := range c.bypass {
+		if b == cls {
+			return true
+		}
+	}
+	return false
+}
+
+type causer interface {
+	Cause() error
+}
+
+type unwrapper interface {
+	Unwrap() error
+}
+
+type multiUnwrapper interface {
+	Unwrap() []error
+}
+
+// wrappedErrors is implemented by *multierror.Error.
+type wrappedErrors interface {
+	WrappedErrors() []error
+}
+
+// errorser is implemented by multierr aggregates.
+type errorser interface {
+	Errors() []error
+}
+
+var maxChainLength atomic.Uint64
+
+func init() {
+	maxChainLength.Store(DefaultMaxChainLength)
+}
+
+// DefaultMaxChainLength is the default limit on the number of errors that a
+// single traversal will step through.
+const DefaultMaxChainLength = 1 << 12
+
+// SetMaxChainLength sets the limit on the number of errors that a single
+// traversal will step through, across every branch of the chain, as a
+// safeguard against pathological chains. Traversal stops without a match
+// once the limit is reached. A limit of 0 disables the safeguard.
+//
+// Linear chains that loop back on themselves are detected and cut short
+// regardless of the limit. It is safe for concurrent use.
+func SetMaxChainLength(n uint) {
+	maxChainLength.Store(uint64(n))
+}
+
+var (
+	unwrappersMu sync.Mutex
+	unwrappers   atomic.Value // []*func(error) []error
+
+	// unwrappersGen counts changes to unwrappers, so that class indexes
+	// built with others can be rebuilt
+	unwrappersGen atomic.Uint64
+)
+
+// RegisterUnwrapper registers a function that traversal will consult to
+// reach the errors beneath err, for error types that use chain interfaces
+// errsel doesn't know about. It should return nil if it doesn't recognize
+// err.
+//
+//    errsel.RegisterUnwrapper(func(err error) []error {
+//        if o, ok := err.(interface{ Origin() error }); ok {
+//            return []error{o.Origin()}
+//        }
+//        return nil
+//    })
+//
+// Registered unwrappers are consulted in order of registration, and only
+// for errors that don't implement a built in chain interface. It is safe
+// for concurrent use, but should typically be called during program
+// initialization.
+//
+// The returned function unregisters f, such as when a test is cleaned up.
+// Calling it more than once has no further effect.
+func RegisterUnwrapper(f func(error) []error) (unregister func()) {
+	unwrappersMu.Lock()
+	defer unwrappersMu.Unlock()
+
+	p := &f
+	fs, _ := unwrappers.Load().([]*func(error) []error)
+	unwrappers.Store(append(fs[:len(fs):len(fs)], p))
+	unwrappersGen.Add(1)
+
+	return func() {
+		unwrappersMu.Lock()
+		defer unwrappersMu.Unlock()
+
+		fs, _ := unwrappers.Load().([]*func(error) []error)
+		kept := make([]*func(error) []error, 0, len(fs))
+		for _, g := range fs {
+			if g != p {
+				kept = append(kept, g)
+			}
+		}
+		unwrappers.Store(kept)
+		unwrappersGen.Add(1)
+	}
+}
+
+// next returns the errors beneath err in its context chain. A single error
+// is returned as next, so that linear chains can be walked without
+// allocating.
+func (c *traverseConfig) next(err error) (next error, multi []error) {
+	if cs, ok := err.(causer); ok && c.follow&FollowCause != 0 {
+		return cs.Cause(), nil
+	}
+	if c.follow&FollowAggregate != 0 {
+		if w, ok := err.(wrappedErrors); ok {
+			return nil, w.WrappedErrors()
+		}
+		if e, ok := err.(errorser); ok {
+			return nil, e.Errors()
+		}
+	}
+	if u, ok := err.(unwrapper); ok && c.follow&FollowUnwrap != 0 {
+		return u.Unwrap(), nil
+	}
+	if u, ok := err.(multiUnwrapper); ok && c.follow&FollowMulti != 0 {
+		return nil, u.Unwrap()
+	}
+	if c.follow&FollowRegistered != 0 {
+		fs, _ := unwrappers.Load().([]*func(error) []error)
+		for _, f := range fs {
+			switch errs := (*f)(err); len(errs) {
+			case 0:
+				continue
+			case 1:
+				return errs[0], nil
+			default:
+				return nil, errs
+			}
+		}
+	}
+	return nil, nil
+}
+
+// frame is a position in the traversal of a context chain.
+type frame struct {
+	err      error
+	lens     uint
+	depth    uint
+	classes  uint
+	shadowed bool
+	above    bool // above the anchor set by LensTo
+}
+
+// cycleCheckAfter is the number of errors on a branch after which cycle
+// detection begins, so that typical chains don't pay for it.
+const cycleCheckAfter = 64
+
+// brent detects cycles in a branch of a context chain, using Brent's
+// algorithm. A buggy chain interface that returns an error above it would
+// otherwise send traversal into an infinite loop.
+type brent struct {
+	steps, power, lam uint
+	seen              error
+}
+
+// cyclic reports whether err has been seen before on the current branch.
+func (b *brent) cyclic(err error) bool {
+	if b.steps++; b.steps <= cycleCheckAfter {
+		return false
+	}
+	if b.seen != nil && sameErr(err, b.seen) {
+		return true
+	}
+	if b.lam++; b.lam >= b.power {
+		b.seen, b.power, b.lam = err, max(1, 2*b.power), 0
+	}
+	return false
+}
+
+// child returns the frame for err, which is directly beneath f.
+func (f frame) child(err error) frame {
+	f.err = err
+	return f
+}
+
+// walk traverses the context chain of err, calling visit with the frame of
+// every error that is not hidden by a lens or past the maximum depth. If visit
+// reports a match, walk returns true and the matched error. If visit reports
+// that it should not descend, errors beneath the visited one are skipped.
+//
+// Linear chains are walked in place; only the branches of multi-errors are
+// held on a pending stack (or queue, if traversing breadth first).
+func (c *traverseConfig) walk(err error, visit func(frame) (match, descend bool)) (bool, error) {
+	if c.reverse {
+		return c.walkReverse(err, visit)
+	}
+
+	lens, lensClasses := c.lens, c.lensClasses
+	if c.fromCause {
+		n := c.length(err)
+		if lens >= n {
+			return false, nil
+		}
+		lens, lensClasses = n-1-lens, false
+	}
+
+	var (
+		buf     [4]frame
+		pending = buf[:0]
+		cur     = frame{err: err, lens: lens, above: c.anchor != nil}
+		limit   = maxChainLength.Load()
+		steps   uint64
+		cycle   brent
+		tr      = c.tracer()
+	)
+
+	for {
+		if steps++; limit != 0 && steps > limit {
+			if tr != nil {
+				traceStep(tr, TraceLimit, cur, false)
+			}
+			return false, nil
+		}
+
+		descend, kind := true, TraceVisit
+		if cycle.cyclic(cur.err) {
+			descend, kind = false, TraceCycle
+		} else if cur.lens > 0 {
+			if _, ok := cur.err.(*classErr); ok || !lensClasses {
+				cur.lens--
+			}
+			kind = TraceLens
+		} else if cur.above {
+			cur.above = !c.anchor(cur.err)
+			kind = TraceAnchor
+		} else if c.until != nil && c.until(cur.err) {
+			descend, kind = false, TraceUntil
+		} else if (c.depth == 0 || cur.depth < c.depth) && (c.classDepth == 0 || cur.classes < c.classDepth) {
+			var match bool
+			match, descend = visit(cur)
+			if tr != nil && !c.quiet {
+				traceStep(tr, TraceVisit, cur, match)
+			}
+			if match {
+				return true, cur.err
+			}
+			if e, ok := cur.err.(*classErr); ok {
+				if e.shadow && !c.bypassed(e.cls) && tr != nil {
+					traceStep(tr, TraceShadow, cur, false)
+				}
+				cur.shadowed = cur.shadowed || e.shadow && !c.bypassed(e.cls)
+				cur.classes++
+			}
+			cur.depth++
+		} else {
+			descend, kind = false, TraceDepth
+		}
+		if tr != nil && kind != TraceVisit {
+			traceStep(tr, kind, cur, false)
+		}
+
+		var (
+			next  error
+			multi []error
+		)
+		if descend {
+			next, multi = c.next(cur.err)
+		}
+
+		switch {
+		case next != nil && (len(pending) == 0 || !c.breadth):
+			cur = cur.child(next)
+			continue
+
+		case next != nil:
+			pending = append(pending, cur.child(next))
+
+		case c.breadth:
+			for _, e := range multi {
+				if e != nil {
+					pending = append(pending, cur.child(e))
+				}
+			}
+
+		default:
+			for i := len(multi) - 1; i >= 0; i-- {
+				if multi[i] != nil {
+					pending = append(pending, cur.child(multi[i]))
+				}
+			}
+		}
+
+		if len(pending) == 0 {
+			return false, nil
+		}
+
+		if c.breadth {
+			cur, pending = pending[0], pending[1:]
+		} else {
+			cur, pending = pending[len(pending)-1], pending[:len(pending)-1]
+		}
+		cycle = brent{}
+	}
+}
+
+// walkReverse collects the frames that walk would visit, then visits them in
+// reverse. Visitors can't prune the collection, so they must skip frames that
+// they wouldn't have descended to themselves.
+func (c *traverseConfig) walkReverse(err error, visit func(frame) (match, descend bool)) (bool, error) {
+	fwd := *c
+	fwd.reverse, fwd.quiet = false, true
+
+	var frames []frame
+	fwd.walk(err, func(f frame) (bool, bool) {
+		frames = append(frames, f)
+		return false, true
+	})
+
+	tr := c.tracer()
+	for i := len(frames) - 1; i >= 0; i-- {
+		match, _ := visit(frames[i])
+		if tr != nil {
+			traceStep(tr, TraceVisit, frames[i], match)
+		}
+		if match {
+			return true, frames[i].err
+		}
+	}
+	return false, nil
+}
+
+// length returns the number of errors on the longest branch of err's context
+// chain.
+func (c *traverseConfig) length(err error) uint {
+	cfg := traverseConfig{follow: c.follow, breadth: c.breadth, quiet: true}
+
+	var n uint
+	cfg.walk(err, func(f frame) (bool, bool) {
+		if f.depth >= n {
+			n = f.depth + 1
+		}
+		return false, true
+	})
+	return n
+}
+
 type root func(error) bool
 
 // Root returns a selector that will apply f to an error. If it returns
@@ -58,41 +641,26 @@ type causes struct {
 // Traversal of intermediates will be done using an efficient, in-place
 // trampoline algorithm with as few allocations as possible.
 func Causes(f func(error) bool, opts ...TraverseOption) Selector {
-	return SelectorFunc(causes{
+	t := causes{
 		f:   f,
 		cfg: applyTraverseOpts(opts...),
-	}.traverse)
+	}
+	return &causesSelector{SelectorFunc: SelectorFunc(t.traverse), causes: t}
 }
 
-type causer interface {
-	Cause() error
+// causesSelector is the selector returned by Causes. It keeps hold of its
+// predicate, so that Compile can fuse it with others.
+type causesSelector struct {
+	SelectorFunc
+	causes
 }
 
 func (t causes) traverse(err error) (bool, error) {
-	cursor := err
-	for lens := t.cfg.lens; lens > 0; lens-- {
-		if c, ok := err.(causer); ok {
-			cursor = c.Cause()
-			continue
-		}
-		break
-	}
-
-	for depth := uint(0); depth < t.cfg.depth || t.cfg.depth == 0; depth++ {
-		e := cursor
-		if t.f(e) {
-			return true, e
-		}
-
-		c, ok := e.(causer)
-		if !ok {
-			return false, nil
-		}
-
-		cursor = c.Cause()
-	}
+	return t.cfg.walk(err, t.visit)
+}
 
-	return false, nil
+func (t causes) visit(f frame) (bool, bool) {
+	return t.f(f.err), true
 }
 
 type classes struct {
@@ -105,8 +673,10 @@ type classes struct {
 // will return true and the intermediate error that f was called with.
 // Otherwise, it will return false and nil.
 //
-// It will respect class shadowing. A lens can be used to skip past shadowing
-// classes, if such behavior is required.
+// It will respect class shadowing; a shadowing class hides only the branch
+// of a multi-error it appears in. A lens can be used to skip past shadowing
+// classes, if such behavior is required; see Lens and ClassLens. Shadowing
+// can also be disabled entirely with IgnoreShadow.
 //
 // Traversal of intermediates will be done using an efficient, in-place
 // trampoline algorithm with as few allocations as possible.
@@ -118,40 +688,19 @@ func Classes(f func(error) bool, opts ...TraverseOption) Selector {
 }
 
 func (t classes) traverse(err error) (bool, error) {
-	cursor, lensCursor := err, err
-	for lens := t.cfg.lens; lens > 0; lens-- {
-		if _, ok := lensCursor.(*classErr); ok {
-			cursor = lensCursor
-		}
+	return t.cfg.walk(err, t.visit)
+}
 
-		if c, ok := err.(causer); ok {
-			lensCursor = c.Cause()
-			continue
-		}
-		break
+func (t classes) visit(f frame) (bool, bool) {
+	if f.shadowed && !t.cfg.unshadow {
+		return false, false
 	}
-
-	var depth uint
-	for depth < t.cfg.depth || t.cfg.depth == 0 {
-		e := cursor
-		if c, ok := e.(*classErr); ok {
-			if t.f(e) {
-				return true, e
-			}
-
-			if c.cls.shadow {
-				return false, nil
-			}
-		}
-
-		c, ok := e.(causer)
-		if !ok {
-			return false, nil
-		}
-
-		cursor = c.Cause()
-		depth++
+	c, ok := f.err.(*classErr)
+	if !ok {
+		return false, true
 	}
-
-	return false, nil
+	if t.f(f.err) {
+		return true, false
+	}
+	return false, !c.shadow || t.cfg.unshadow || t.cfg.bypassed(c.cls)
 }
@@ -0,0 +1,385 @@
+package errsel
+
+import (
+	stderrors "errors"
+	"fmt"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLens(t *testing.T) {
+	outer, inner := AnonymousShadow(), Anonymous()
+
+	// outer#{ wrap: inner{ root } }
+	root := errors.New("root")
+	err := outer.Lift(errors.WithMessage(inner.Lift(root), "wrap"))
+
+	cases := []struct {
+		name string
+		sel  Selector
+		ok   bool
+	}{
+		{"no lens, outer", outer, true},
+		{"no lens, shadowed", inner, false},
+		{"lens 0", Classes(inner.In, Lens(0)), false},
+		{"lens 1, at wrap", Classes(inner.In, Lens(1)), true},
+		{"lens 2, at inner", Classes(inner.In, Lens(2)), true},
+		{"lens 3, past inner", Classes(inner.In, Lens(3)), false},
+		{"lens 1, skips outer", Classes(outer.In, Lens(1)), false},
+		{"lens past chain", Classes(inner.In, Lens(10)), false},
+		{"class lens 1, skips outer", Classes(inner.In, ClassLens(1)), true},
+		{"class lens 2, skips inner", Classes(inner.In, ClassLens(2)), false},
+		{"class lens past chain", Causes(Error(root).In, ClassLens(3)), false},
+		{"causes lens 3, at root", Causes(Error(root).In, Lens(3)), true},
+		{"causes lens 4, past root", Causes(Error(root).In, Lens(4)), false},
+		{"causes class lens 2, at root", Causes(Error(root).In, ClassLens(2)), true},
+		{"from cause 0, at root", Causes(Error(root).In, LensFromCause(0)), true},
+		{"from cause 0, skips inner", Classes(inner.In, LensFromCause(0)), false},
+		{"from cause 1, at inner", Classes(inner.In, LensFromCause(1)), true},
+		{"from cause 2, skips outer", Classes(outer.In, LensFromCause(2)), false},
+		{"from cause 3, at outer", Classes(outer.In, LensFromCause(3)), true},
+		{"from cause past chain", Causes(Error(root).In, LensFromCause(4)), false},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			assert.Equal(t, c.ok, c.sel.In(err))
+		})
+	}
+}
+
+func TestLensTo(t *testing.T) {
+	api, storage, timeout := Named("api"), Named("storage"), Named("timeout")
+
+	// api{ wrap: timeout{ storage{ timeout{ root } } } }
+	root := errors.New("root")
+	err := api.Lift(errors.Wrap(timeout.Lift(storage.Lift(timeout.Lift(root))), "wrap"))
+	isRoot := func(e error) bool { return e == root }
+
+	cases := []struct {
+		name string
+		sel  Selector
+		ok   bool
+		er   error
+	}{
+		{"beneath storage", Classes(atFrame(timeout), LensTo(storage)), true, timeout.Lift(root)},
+		{"storage skipped", Classes(atFrame(storage), LensTo(storage)), false, nil},
+		{"api skipped", Classes(atFrame(api), LensTo(timeout)), false, nil},
+		{"missing anchor", Causes(isRoot, LensTo(Named("missing"))), false, nil},
+		{"selector anchor", Classes(atFrame(timeout), LensTo(Error(timeout.Lift(root)))), false, nil},
+		{"with depth", Causes(isRoot, LensTo(storage), Depth(1)), false, nil},
+		{"with lens", Classes(atFrame(timeout), Lens(1), LensTo(timeout)), true, timeout.Lift(root)},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			ok, er := c.sel.Traverse(err)
+			assert.Equal(t, c.ok, ok)
+			if c.er != nil && assert.NotNil(t, er) {
+				assert.Equal(t, c.er.Error(), er.Error())
+			}
+		})
+	}
+}
+
+func TestBetween(t *testing.T) {
+	api, service, storage, timeout := Named("api"), Named("service"), Named("storage"), Named("timeout")
+	at := atFrame(timeout)
+
+	// api{ timeout{ service{ wrap: timeout{ storage{ timeout{ root } } } } } }
+	inner := timeout.Lift(storage.Lift(timeout.New("root")))
+	err := api.Lift(timeout.Lift(service.Lift(errors.Wrap(inner, "wrap"))))
+
+	ok, er := Classes(at, Between(service, storage)).Traverse(err)
+	assert.True(t, ok)
+	assert.Equal(t, inner, er)
+
+	assert.False(t, Classes(at, Between(service, timeout)).In(err))
+	assert.False(t, Classes(at, Between(Named("missing"), storage)).In(err))
+	assert.True(t, Classes(atFrame(storage), Between(service, Named("missing"))).In(err))
+	assert.False(t, Classes(at, Between(storage, timeout)).In(err))
+	assert.True(t, Causes(at, Between(storage, api)).In(err))
+}
+
+func TestReverse(t *testing.T) {
+	a, b, shadow := Named("a"), Named("b"), NamedShadow("shadow")
+	at := atFrame(a)
+
+	// a{ wrap: b{ a{ shadow#{ a{ root } } } } }
+	hidden := a.New("root")
+	inner := a.Lift(shadow.Lift(hidden))
+	err := a.Lift(errors.Wrap(b.Lift(inner), "wrap"))
+
+	ok, er := Classes(at).Traverse(err)
+	assert.True(t, ok)
+	assert.Equal(t, err, er)
+
+	ok, er = Classes(at, Reverse()).Traverse(err)
+	assert.True(t, ok)
+	assert.Equal(t, inner, er)
+
+	ok, er = Classes(at, Reverse(), IgnoreShadow()).Traverse(err)
+	assert.True(t, ok)
+	assert.Equal(t, hidden, er)
+
+	ok, er = Causes(func(error) bool { return true }, Reverse()).Traverse(err)
+	assert.True(t, ok)
+	assert.Equal(t, "root", er.Error())
+
+	assert.False(t, Classes(at, Reverse(), Depth(1), Lens(1)).In(err))
+	assert.False(t, Classes(atFrame(b), Reverse()).In(hidden))
+}
+
+func TestClassDepth(t *testing.T) {
+	a, b, c := Named("a"), Named("b"), Named("c")
+
+	// a{ wrap: b{ wrap: c{ root } } }
+	err := a.Lift(errors.Wrap(b.Lift(errors.Wrap(c.New("root"), "wrap")), "wrap"))
+
+	cases := []struct {
+		name string
+		sel  Selector
+		ok   bool
+	}{
+		{"outermost", Classes(atFrame(a), ClassDepth(1)), true},
+		{"second", Classes(atFrame(b), ClassDepth(1)), false},
+		{"second, depth 2", Classes(atFrame(b), ClassDepth(2)), true},
+		{"second, raw depth 2", Classes(atFrame(b), Depth(2)), false},
+		{"third, depth 2", Classes(atFrame(c), ClassDepth(2)), false},
+		{"third, depth 3", Classes(atFrame(c), ClassDepth(3)), true},
+		{"both limits", Classes(atFrame(c), ClassDepth(3), Depth(4)), false},
+		{"with class lens", Classes(atFrame(c), ClassLens(1), ClassDepth(2)), true},
+		{"causes", Causes(func(e error) bool { return e == errors.Cause(err) }, ClassDepth(2)), false},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			assert.Equal(t, c.ok, c.sel.In(err))
+		})
+	}
+}
+
+func TestFollow(t *testing.T) {
+	root := errors.New("root")
+	cls := Anonymous()
+
+	// a chain mixing pkg/errors causers and stdlib wrapping
+	err := fmt.Errorf("std: %w", errors.Wrap(cls.Lift(fmt.Errorf("std: %w", root)), "pkg"))
+
+	cases := []struct {
+		name string
+		sel  Selector
+		ok   bool
+	}{
+		{"default, root", Error(root), true},
+		{"default, class", cls, true},
+		{"cause only, class", Classes(cls.In, Follow(FollowCause)), false},
+		{"unwrap only, class", Classes(cls.In, Follow(FollowUnwrap)), false},
+		{"unwrap only, lensed", Causes(Error(root).In, Lens(3), Follow(FollowUnwrap)), false},
+		{"all, lensed", Causes(Error(root).In, Lens(4), Follow(FollowAll)), true},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			assert.Equal(t, c.ok, c.sel.In(err))
+		})
+	}
+}
+
+func TestMultiTraverse(t *testing.T) {
+	a, b, shadow := Named("a"), Named("b"), NamedShadow("shadow")
+	deep, shallow := errors.New("deep"), errors.New("shallow")
+
+	// join{ wrap: a{ deep }, shadow#{ b{ ... } }, b{ shallow } }
+	leaf := func(e error) bool { return e == deep || e == shallow }
+	isDeep := func(e error) bool { return e == deep }
+	isShallow := func(e error) bool { return e == shallow }
+
+	err := stderrors.Join(
+		errors.Wrap(a.Lift(deep), "wrap"),
+		shadow.Lift(b.New("hidden")),
+		b.Lift(shallow),
+	)
+
+	cases := []struct {
+		name string
+		sel  Selector
+		ok   bool
+		er   error
+	}{
+		{"class in first branch", a, true, a.Lift(deep)},
+		{"shadow hides one branch", b, true, b.Lift(shallow)},
+		{"depth first", Causes(leaf), true, deep},
+		{"breadth first", Causes(leaf, BreadthFirst()), true, shallow},
+		{"depth per branch", Causes(isShallow, Depth(3)), true, shallow},
+		{"depth too shallow", Causes(isDeep, Depth(3)), false, nil},
+		{"lens per branch", Causes(isShallow, Lens(2)), true, shallow},
+		{"lens from deepest cause", Causes(leaf, LensFromCause(1)), true, deep},
+		{"lens from cause, shorter branch", Causes(isShallow, LensFromCause(0)), false, nil},
+		{"class lens per branch", Classes(b.In, ClassLens(1)), true, b.Lift(errors.New("hidden"))},
+		{"multi not followed", Classes(a.In, Follow(FollowCause|FollowUnwrap)), false, nil},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			ok, er := c.sel.Traverse(err)
+			assert.Equal(t, c.ok, ok)
+			if c.er != nil {
+				assert.Equal(t, c.er.Error(), er.Error())
+			}
+		})
+	}
+}
+
+// hashiErr mimics *multierror.Error from github.com/hashicorp/go-multierror,
+// including its linear Unwrap.
+type hashiErr struct{ errs []error }
+
+func (e *hashiErr) Error() string          { return "hashi" }
+func (e *hashiErr) WrappedErrors() []error { return e.errs }
+func (e *hashiErr) Unwrap() error          { return e.errs[0] }
+
+// uberErr mimics aggregates from go.uber.org/multierr.
+type uberErr struct{ errs []error }
+
+func (e *uberErr) Error() string   { return "uber" }
+func (e *uberErr) Errors() []error { return e.errs }
+
+func TestAggregateTraverse(t *testing.T) {
+	cls := Named("fanout")
+	first, last := errors.New("first"), cls.New("last")
+
+	for _, err := range []error{
+		&hashiErr{[]error{first, last}},
+		errors.Wrap(&uberErr{[]error{first, last}}, "wrapped"),
+	} {
+		t.Run(err.Error(), func(t *testing.T) {
+			assert.True(t, cls.In(err))
+			assert.True(t, Error(first).In(err))
+			assert.False(t, Classes(cls.In, Follow(FollowCause|FollowUnwrap)).In(err))
+		})
+	}
+}
+
+type originErr struct{ origin error }
+
+func (e originErr) Error() string { return "origin" }
+func (e originErr) Origin() error { return e.origin }
+
+type causesErr struct{ causes []error }
+
+func (e causesErr) Error() string  { return "causes" }
+func (e causesErr) Cause() []error { return e.causes }
+
+func TestRegisterUnwrapper(t *testing.T) {
+	cls := Named("registered")
+	err := originErr{causesErr{[]error{errors.New("a"), cls.New("b")}}}
+	outer := Named("outer").Lift(err)
+
+	assert.False(t, cls.In(err))
+	assert.False(t, cls.In(outer))
+
+	t.Cleanup(RegisterUnwrapper(func(err error) []error {
+		if o, ok := err.(interface{ Origin() error }); ok {
+			return []error{o.Origin()}
+		}
+		return nil
+	}))
+	unregister := RegisterUnwrapper(func(err error) []error {
+		if c, ok := err.(interface{ Cause() []error }); ok {
+			return c.Cause()
+		}
+		return nil
+	})
+
+	assert.True(t, cls.In(err))
+	assert.True(t, cls.In(outer))
+	assert.False(t, Classes(cls.In, Follow(FollowCause)).In(err))
+
+	unregister()
+	unregister()
+	assert.False(t, cls.In(err))
+	assert.False(t, cls.In(outer))
+}
+
+// loopErr is a buggy causer whose chain loops back on itself.
+type loopErr struct {
+	name string
+	next *loopErr
+}
+
+func (e *loopErr) Error() string { return e.name }
+func (e *loopErr) Cause() error  { return e.next }
+
+func TestCycles(t *testing.T) {
+	self := &loopErr{name: "self"}
+	self.next = self
+
+	a, b := &loopErr{name: "a"}, &loopErr{name: "b"}
+	a.next, b.next = b, a
+
+	never := func(error) bool { return false }
+	for _, err := range []error{
+		self,
+		errors.Wrap(a, "wrap"),
+		stderrors.Join(b, Named("cls").Lift(a)),
+	} {
+		t.Run(err.Error(), func(t *testing.T) {
+			assert.False(t, Causes(never).In(err))
+			assert.False(t, Causes(never, BreadthFirst()).In(err))
+			assert.True(t, Causes(func(e error) bool { return e == self || e == b }).In(err))
+
+			n := 0
+			Walk(err, func(error, FrameInfo) bool { n++; return true })
+			assert.True(t, n < 4*cycleCheckAfter)
+		})
+	}
+}
+
+func TestMaxChainLength(t *testing.T) {
+	root := errors.New("root")
+	err := root
+	for i := 0; i < 100; i++ {
+		err = fmt.Errorf("wrap %d: %w", i, err)
+	}
+	isRoot := Causes(func(e error) bool { return e == root })
+
+	assert.True(t, isRoot.In(err))
+
+	SetMaxChainLength(50)
+	defer SetMaxChainLength(DefaultMaxChainLength)
+	assert.False(t, isRoot.In(err))
+
+	SetMaxChainLength(0)
+	assert.True(t, isRoot.In(err))
+}
+
+func TestBypass(t *testing.T) {
+	var outerToken, innerToken, zero Bypass
+	outer := NamedShadow("outer", MintBypass(&outerToken))
+	inner := NamedShadow("inner", MintBypass(&innerToken))
+	a, b := Named("a"), Named("b")
+
+	// outer#{ a{ inner#{ b{ root } } } }
+	err := outer.Lift(a.Lift(inner.Lift(b.New("root"))))
+
+	assert.False(t, Classes(atFrame(a)).In(err))
+	assert.True(t, Classes(atFrame(a), Through(outerToken)).In(err))
+	assert.False(t, Classes(atFrame(b), Through(outerToken)).In(err))
+	assert.False(t, Classes(atFrame(b), Through(innerToken)).In(err))
+	assert.True(t, Classes(atFrame(b), Through(outerToken, innerToken)).In(err))
+	assert.False(t, Classes(atFrame(a), Through(zero)).In(err))
+
+	// an equivalent class constructed elsewhere can't forge the capability
+	var forged Bypass
+	NamedShadow("outer", MintBypass(&forged))
+	assert.False(t, Classes(atFrame(a), Through(forged)).In(err))
+
+	_, ok := MetaOf(outer.Lift(Named("m", Meta("k", 1)).New("x")), "k", Through(outerToken))
+	assert.True(t, ok)
+	v, ok := ValueOf(outer.Lift(a.WithValue(errors.New("x"), valueKey("k"), 1)), valueKey("k"), Through(outerToken))
+	assert.True(t, ok)
+	assert.Equal(t, 1, v)
+}
@@ -0,0 +1,56 @@
+package errsel
+
+// valueErr annotates an error with a value under a key, much like
+// context.WithValue. It is transparent in the error's message.
+type valueErr struct {
+	err      error
+	key, val interface{}
+}
+
+func withValue(err error, key, val interface{}) error {
+	if err == nil {
+		return nil
+	}
+	return &valueErr{
+		err: err,
+		key: key,
+		val: val,
+	}
+}
+
+func (v *valueErr) Error() string {
+	return v.err.Error()
+}
+
+func (v *valueErr) Cause() error {
+	return v.err
+}
+
+func (v *valueErr) Unwrap() error {
+	return v.err
+}
+
+// ValueOf returns the value attached under key nearest to the outside of
+// err's context chain, respecting class shadowing. Values attached beneath
+// a shadowing class are not visible. Like context.Value, keys should be of
+// an unexported type to avoid collisions.
+//
+//    type requestIDKey struct{}
+//
+//    err = class.WithValue(err, requestIDKey{}, "abc123")
+//    id, ok := ValueOf(err, requestIDKey{})
+//
+// Any provided traverse options will scope to causes.
+func ValueOf(err error, key interface{}, opts ...TraverseOption) (interface{}, bool) {
+	var (
+		val   interface{}
+		found bool
+	)
+	Walk(err, func(e error, info FrameInfo) bool {
+		if v, ok := e.(*valueErr); ok && !info.Shadowed && v.key == key {
+			val, found = v.val, true
+		}
+		return !found
+	}, opts...)
+	return val, found
+}
@@ -0,0 +1,39 @@
+package errsel
+
+import (
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+type valueKey string
+
+func TestValueOf(t *testing.T) {
+	cls, shadow := Named("request"), AnonymousShadow()
+
+	err := cls.WithValue(errors.New("timeout"), valueKey("id"), "inner")
+	err = errors.Wrap(err, "handler")
+	err = LifterFunc(shadow.Lift).WithValue(err, valueKey("id"), "outer")
+
+	assert.Equal(t, "handler: request{ timeout }", err.Error())
+
+	v, ok := ValueOf(err, valueKey("id"))
+	assert.True(t, ok)
+	assert.Equal(t, "outer", v)
+
+	v, ok = ValueOf(errors.Wrap(cls.WithValue(errors.New("x"), valueKey("id"), "only"), "wrap"), valueKey("id"))
+	assert.True(t, ok)
+	assert.Equal(t, "only", v)
+
+	hidden := shadow.Lift(cls.WithValue(errors.New("x"), valueKey("id"), "hidden"))
+	_, ok = ValueOf(hidden, valueKey("id"))
+	assert.False(t, ok)
+	v, _ = ValueOf(hidden, valueKey("id"), IgnoreShadow())
+	assert.Equal(t, "hidden", v)
+
+	_, ok = ValueOf(err, valueKey("missing"))
+	assert.False(t, ok)
+
+	assert.Nil(t, cls.WithValue(nil, valueKey("id"), "x"))
+}
@@ -0,0 +1,71 @@
+package errsel
+
+import "iter"
+
+// FrameInfo describes an error's position in a context chain during Walk.
+type FrameInfo struct {
+	// Depth is the number of errors above this one on its branch of the
+	// chain, not counting any skipped by a lens.
+	Depth uint
+
+	// Class is the class this error was lifted into, or nil if it isn't
+	// a class annotation.
+	Class Class
+
+	// Bound holds any further classes this error was lifted into, from the
+	// outermost inward, if it was lifted into a bound class; see Bind.
+	Bound []Class
+
+	// Shadow reports whether this error is a shadowing class annotation.
+	Shadow bool
+
+	// Shadowed reports whether this error is hidden beneath a shadowing
+	// class annotation, and would not be visited by Classes. It is always
+	// false if IgnoreShadow is provided.
+	Shadowed bool
+}
+
+// Walk calls fn with every intermediate error in err's context chain,
+// including those hidden beneath a shadowing class, in the order that
+// Causes would visit them. Walking stops when fn returns false.
+//
+// Walk is a building block for tools that need to inspect the structure of
+// a chain; it follows the same traversal rules as every selector.
+func Walk(err error, fn func(e error, info FrameInfo) bool, opts ...TraverseOption) {
+	if err == nil {
+		return
+	}
+	cfg := applyTraverseOpts(opts...)
+	cfg.walk(err, func(f frame) (bool, bool) {
+		info := FrameInfo{
+			Depth:    f.depth,
+			Shadowed: f.shadowed && !cfg.unshadow,
+		}
+		if c, ok := f.err.(*classErr); ok {
+			info.Class = c.cls.self
+			for _, cls := range c.also {
+				info.Bound = append(info.Bound, cls.self)
+			}
+			info.Shadow = c.shadow
+		}
+		return !fn(f.err, info), true
+	})
+}
+
+// Chain returns an iterator over every intermediate error in err's context
+// chain, in the order that Causes would visit them.
+//
+//    for e := range errsel.Chain(err, errsel.Lens(1)) {
+//        // inspect e
+//    }
+func Chain(err error, opts ...TraverseOption) iter.Seq[error] {
+	cfg := applyTraverseOpts(opts...)
+	return func(yield func(error) bool) {
+		if err == nil {
+			return
+		}
+		cfg.walk(err, func(f frame) (bool, bool) {
+			return !yield(f.err), true
+		})
+	}
+}